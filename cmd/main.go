@@ -2,16 +2,141 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"yadro_test/internal/cfg"
 	cmptmgr "yadro_test/internal/competitionMgr"
+	"yadro_test/internal/corrections"
+	"yadro_test/internal/eventstore"
+	"yadro_test/internal/i18n"
 	cl "yadro_test/internal/logger"
+	"yadro_test/internal/merger"
+	"yadro_test/internal/races"
+	"yadro_test/internal/validator"
 )
 
 func main() { //Я не фанат комментариев и считаю, что код в go вполне себе самодокументируем, но мне посоветовали написать комментарии в тестовом, поэтому пишу
-	logFile, err := os.OpenFile("output.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666) //Открываем файл для логов
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "merge": //Подкоманда merge: склеиваем файлы с разных устройств хронометража в один поток
+			runMerge(os.Args[2:], cfg.MustLoad())
+			return
+		case "validate": //Подкоманда validate: только ищем проблемы во входном файле, результаты не считаем
+			os.Exit(runValidate(os.Args[2:]))
+		case "races": //Подкоманда races: несколько гонок фестиваля в одном процессе, события маршрутизируются по id гонки
+			runRaces(os.Args[2:])
+			return
+		}
+	}
+
+	cfg := cfg.MustLoad() //Конфиг
+
+	eventsPath := flag.String("events", "events", "events file")
+	formatName := flag.String("format", "auto", "events format: text, jsonl or auto")
+	lang := flag.String("lang", cfg.Lang, "language of the log, report and diagnostics: en or ru")
+	jsonLogPath := flag.String("json-log", "", "also write the event log as JSON lines to this file")
+	logStdout := flag.Bool("log-stdout", false, "also print the human-readable event log to stdout")
+	raceId := flag.String("race", cfg.RaceId, "race id, used in the log file name")
+	storePath := flag.String("store", "", "event store file: accepted events are saved there and replayed after a restart")
+	snapshotPath := flag.String("snapshot", "", "write the full race state to this file when the input ends, before the race is finalized")
+	restorePath := flag.String("restore", "", "continue the race from a snapshot instead of starting from scratch")
+	correctionsPath := flag.String("corrections", "", "jury corrections(void, replace, insert events) applied on top of the events file")
+	adjustmentsPath := flag.String("adjustments", "", "jury time penalties and credits(competitor, delta, reason) added to the total time")
+	flag.Parse()
+	format, err := cl.FormatByName(*formatName)
+	if err != nil {
+		loc, _ := i18n.New(*lang) //Неизвестный язык отсечётся позже, здесь хватит английского
+		log.Fatal(loc.Localize(err))
+	}
+	cfg.Lang = *lang
+	cfg.RaceId = *raceId
+	setFlags := make(map[string]bool) //Флаги, заданные явно: со снимком они главнее его конфига
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	inputFile, err := os.Open(*eventsPath) //Инпут файл
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer inputFile.Close()
+
+	runCompetition(inputFile, cfg, runOptions{format: format, jsonLogPath: *jsonLogPath, logStdout: *logStdout, storePath: *storePath, snapshotPath: *snapshotPath, restorePath: *restorePath, correctionsPath: *correctionsPath, adjustmentsPath: *adjustmentsPath, setFlags: setFlags})
+}
+
+type runOptions struct { //Настройки запуска, которые приходят из флагов
+	format          cl.InputFormat
+	jsonLogPath     string
+	logStdout       bool
+	storePath       string
+	snapshotPath    string
+	restorePath     string
+	correctionsPath string
+	adjustmentsPath string
+	setFlags        map[string]bool //Какие флаги заданы в командной строке явно
+}
+
+func runCompetition(input io.Reader, cfg *cfg.Config, opts runOptions) {
+	var audit []corrections.AuditEntry
+	if opts.correctionsPath != "" { //Исправления применяются ко всему потоку сразу, результаты считаются по исправленному потоку с нуля
+		if opts.storePath != "" || opts.restorePath != "" {
+			log.Fatal("-corrections can`t be combined with -store or -restore")
+		}
+		var err error
+		input, audit, err = applyCorrections(input, opts)
+		if err != nil {
+			loc, _ := i18n.New(cfg.Lang) //Со снимком флаг не сочетается, так что язык уже окончательный
+			log.Fatal(loc.Localize(err))
+		}
+		opts.format = cl.TextFormat{}
+	}
+
+	var adjustments []cmptmgr.Adjustment
+	if opts.adjustmentsPath != "" {
+		var err error
+		adjustments, err = readAdjustments(opts.adjustmentsPath)
+		if err != nil {
+			loc, _ := i18n.New(cfg.Lang)
+			log.Fatal(loc.Localize(err))
+		}
+	}
+
+	var snapshot *cmptmgr.Snapshot
+	if opts.restorePath != "" { //Продолжаем гонку со снимка: конфиг тоже берём оттуда, чтобы результаты считались по тем же правилам
+		var err error
+		snapshot, err = readSnapshot(opts.restorePath)
+		if err != nil {
+			loc, _ := i18n.New(cfg.Lang) //Язык снимка ещё неизвестен, ошибку показываем на языке конфига
+			log.Fatal(loc.Localize(err))
+		}
+		langFlag, raceFlag := cfg.Lang, cfg.RaceId
+		cfg = snapshot.Config
+		if opts.setFlags["lang"] { //Язык и id гонки на результаты не влияют, поэтому явные -lang и -race применяем и поверх снимка
+			cfg.Lang = langFlag
+		}
+		if opts.setFlags["race"] {
+			cfg.RaceId = raceFlag
+		}
+	}
+
+	loc, err := i18n.New(cfg.Lang) //Один и тот же язык для лога и для менеджера
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cmptmgr.ValidateRules(cfg.Rules); err != nil {
+		log.Fatal(loc.Localize(err))
+	}
+	if err := cmptmgr.RegisterCloseEvent(cfg.CloseEventId); err != nil {
+		log.Fatal(err)
+	}
+
+	logFile, err := openRaceLog(cfg) //Открываем файл для логов: у каждой гонки свой, с заголовком и ротацией по размеру
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -23,30 +148,354 @@ func main() { //Я не фанат комментариев и считаю, ч
 	}
 	defer outFile.Close()
 
-	inputFile, err := os.Open("events") //Инпут файл
+	diagFile, err := os.OpenFile("diagnostics.txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666) //Сюда попадут дубликаты, конфликты и прочие аномалии во входных данных
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer inputFile.Close()
+	defer diagFile.Close()
 
-	cfg := cfg.MustLoad()                                  //Конфиг
-	l := cl.NewCustomLogger(logFile)                       //Будет закидывать кастомные логи в файл
+	logOpts := []cl.Option{cl.WithLocalizer(loc)}
+	if opts.jsonLogPath != "" { //Структурный лог для внешних инструментов анализа
+		jsonLogFile, err := os.OpenFile(opts.jsonLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer jsonLogFile.Close()
+		logOpts = append(logOpts, cl.WithJSONLog(jsonLogFile))
+	}
+	if opts.logStdout {
+		logOpts = append(logOpts, cl.WithSink(os.Stdout, cl.SinkText))
+	}
+
+	parser := cl.NewParser(opts.format)                    //Разбирает строки инпута в события
+	l := cl.NewCustomLogger(logFile, logOpts...)           //Будет закидывать кастомные логи в файл
 	cmptMgr := cmptmgr.NewCompetitionManager(outFile, cfg) //Отвечает за бизнес-логику и обработку событий(эвентов)
+	if snapshot != nil {
+		cmptMgr = snapshot.Restore(outFile)
+	}
+	cmptMgr.AddObserver(l) //В лог попадают только события, которые менеджер принял
+	cmptMgr.SetCorrections(audit)
+	cmptMgr.SetAdjustments(adjustments)
 
-	scanner := bufio.NewScanner(inputFile)
-	for scanner.Scan() { // Идём по каждой строчке и передаём её в логгер
+	var stored []cl.EventInfo //События, сохранённые до перезапуска: состояние восстанавливаем из них, а в инпуте их пропускаем
+	if opts.storePath != "" {
+		store, err := eventstore.Open(opts.storePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+		stored = store.Events()
+		if cmptMgr.EventCount() < len(stored) { //Со снимка доигрываем только то, что попало в журнал уже после него
+			if err := cmptMgr.Replay(stored[cmptMgr.EventCount():]); err != nil {
+				log.Fatal(err)
+			}
+		}
+		cmptMgr.SetJournal(store)
+	}
+	skip := cmptMgr.EventCount() //Столько строк инпута уже учтено в снимке или журнале
+
+	scanner := bufio.NewScanner(input)
+	for lineNum := 0; scanner.Scan(); lineNum += 1 { // Идём по каждой строчке и передаём её в парсер
 		line := scanner.Text()
-		eventInfo, err := l.ProcessLine(line)
+		eventInfo, err := parser.ParseLine(line)
+		if err != nil {
+			log.Fatalf("Parser(ParseLine) error: %v", loc.Localize(err))
+		}
+		if lineNum < skip {
+			if lineNum < len(stored) && !sameEvent(stored[lineNum], eventInfo) {
+				log.Fatalf("input line %d does not match the event store, use a different -store file for a different input", lineNum+1)
+			}
+			continue
+		}
+		err = cmptMgr.HandleEvent(eventInfo)       //Затем обрабатываем событие менеджером, он же отдаст его логгеру
+		if errors.Is(err, cmptmgr.ErrRaceClosed) { //Гонка закрыта по времени, событием оператора или все уже финишировали - отчёт уже записан
+			log.Printf("CompetitorManager(HandleEvent): %v, the rest of the input is ignored", err)
+			break
+		}
+		if err != nil {
+			log.Fatalf("CompetitorManager(HandleEvent) error: %v", loc.Localize(err))
+		}
+	}
+	if opts.snapshotPath != "" { //Снимок до финализации, чтобы гонку можно было продолжить с него
+		if err := writeSnapshot(opts.snapshotPath, cmptMgr); err != nil {
+			log.Fatal(loc.Localize(err))
+		}
+	}
+	err = cmptMgr.Close() //Когда мы прошли все строчки инпут файла - финализируем статусы и генерируем final report, если гонка ещё не закрыта, на это работа программы закончена
+	if err != nil {
+		log.Fatalf("CompetitorManager(Close) error: %v", err)
+	}
+	err = cmptMgr.WriteDiagnostics(diagFile)
+	if err != nil {
+		log.Fatalf("CompetitorManager(WriteDiagnostics) error: %v", err)
+	}
+}
+
+func applyCorrections(input io.Reader, opts runOptions) (io.Reader, []corrections.AuditEntry, error) {
+	f, err := os.Open(opts.correctionsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	list, err := corrections.Parse(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parser := cl.NewParser(opts.format)
+	var events []cl.EventInfo
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		eventInfo, err := parser.ParseLine(scanner.Text())
 		if err != nil {
-			log.Fatalf("LogHandler(ProcessLine) error: %v", err)
+			return nil, nil, fmt.Errorf("Parser(ParseLine) error: %w", err)
 		}
-		err = cmptMgr.HandleEvent(eventInfo) //Затем после обработки лога обрабатываем её менеджером
+		events = append(events, eventInfo)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	corrected, audit, err := corrections.Apply(events, list, cl.NewParser(nil))
+	if err != nil {
+		return nil, nil, err
+	}
+	lines := make([]string, 0, len(corrected))
+	for _, e := range corrected {
+		lines = append(lines, cl.TextFormat{}.Format(e))
+	}
+	return strings.NewReader(strings.Join(lines, "\n")), audit, nil
+}
+
+func readAdjustments(path string) ([]cmptmgr.Adjustment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return cmptmgr.ParseAdjustments(f)
+}
+
+func readSnapshot(path string) (*cmptmgr.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return cmptmgr.ReadSnapshot(f)
+}
+
+func writeSnapshot(path string, cm *cmptmgr.CompetitionManager) error { //Пишем во временный файл и переименовываем, чтобы не оставить половину снимка
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := cm.WriteSnapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func sameEvent(a, b cl.EventInfo) bool {
+	return a.EventId == b.EventId && a.CompetitorId == b.CompetitorId && a.EventTime.Equal(b.EventTime) && a.ExtraParams == b.ExtraParams
+}
+
+func openRaceLog(cfg *cfg.Config) (*cl.RotatingFile, error) {
+	raceDate := time.Now()
+	if cfg.RaceDate != "" {
+		date, err := time.Parse("2006-01-02", cfg.RaceDate)
 		if err != nil {
-			log.Fatalf("CompetitorManager(HandleEvent) error: %v", err)
+			return nil, fmt.Errorf("invalid raceDate(%s), expected YYYY-MM-DD", cfg.RaceDate)
 		}
+		raceDate = date
+	}
+	header, err := cl.RaceHeader(cfg.RaceId, time.Now(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cl.OpenRotatingFile(cl.RaceLogPath(cfg.LogDir, cfg.RaceId, raceDate), cfg.MaxLogSize, header)
+}
+
+func runMerge(args []string, cfg *cfg.Config) { //go run main.go merge [-o file | -run] start_events range_events finish_events
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	outPath := fs.String("o", "merged_events", "file to write the merged event stream to")
+	process := fs.Bool("run", false, "feed the merged stream directly into the competition manager instead of writing a file")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatal("merge: at least one events file is required")
 	}
-	err = cmptMgr.GenerateReport() //Когда мы прошли все строчки инпут файла - генерируем final report, на это работа программы закончена
+
+	offsets, err := merger.ParseOffsets(cfg.ClockOffsets)
 	if err != nil {
-		log.Fatalf("CompetitorManager(GenerateReport) error: %v", err)
+		log.Fatalf("merge: %v", err)
+	}
+	res, err := merger.MergeFiles(fs.Args(), merger.Options{Offsets: offsets, SyncEventId: cfg.SyncEventId})
+	if err != nil {
+		log.Fatalf("merge error: %v", err)
+	}
+	for name, offset := range res.Offsets {
+		if offset != 0 {
+			log.Printf("merge: clock offset %v applied to %s", offset, name)
+		}
+	}
+	if res.Duplicates > 0 {
+		log.Printf("merge: %d duplicate events removed", res.Duplicates)
+	}
+
+	merged := strings.Join(res.Lines, "\n")
+	if *process { //Сразу отдаём объединённый поток менеджеру, как будто это обычный файл events
+		runCompetition(strings.NewReader(merged), cfg, runOptions{format: cl.TextFormat{}})
+		return
+	}
+	if err := os.WriteFile(*outPath, []byte(merged+"\n"), 0644); err != nil {
+		log.Fatalf("merge: unable to write %s: %v", *outPath, err)
+	}
+}
+
+func runValidate(args []string) int { //go run main.go validate [-config path] [-format text|jsonl|auto] events
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	cfgPath := fs.String("config", cfg.DefaultPath, "config file")
+	formatName := fs.String("format", "auto", "events format: text, jsonl or auto")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Print("validate: exactly one events file is required")
+		return validator.ExitErrors
+	}
+
+	config, err := cfg.Load(*cfgPath)
+	if err != nil {
+		log.Print(err)
+		return validator.ExitErrors
+	}
+	format, err := cl.FormatByName(*formatName)
+	if err != nil {
+		log.Print(err)
+		return validator.ExitErrors
+	}
+	inputFile, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Print(err)
+		return validator.ExitErrors
+	}
+	defer inputFile.Close()
+
+	report, err := validator.Validate(inputFile, config, format)
+	if err != nil {
+		log.Print(err)
+		return validator.ExitErrors
+	}
+	if err := report.Write(os.Stdout); err != nil {
+		log.Print(err)
+	}
+	return report.ExitCode() //0 - всё чисто, 1 - только предупреждения, 2 - есть ошибки
+}
+
+func runRaces(args []string) { //go run main.go races [-races races.json] [-format text|jsonl|auto] events
+	fs := flag.NewFlagSet("races", flag.ExitOnError)
+	racesPath := fs.String("races", "races.json", "races file: race id -> config file")
+	formatName := fs.String("format", "auto", "events format after the race id: text, jsonl or auto")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("races: exactly one events file is required")
+	}
+
+	configs, err := races.LoadConfigs(*racesPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	format, err := cl.FormatByName(*formatName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	inputFile, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer inputFile.Close()
+
+	registry := races.NewRegistry()
+	defer registry.Stop()
+	locs := make(map[string]i18n.Localizer, len(configs)) //Ошибки во входных данных гонки - на её языке
+	diagFiles := make(map[string]*os.File, len(configs))
+	for _, config := range configs { //У каждой гонки свои лог, отчёт и диагностика: <logDir>/<raceId>_<raceDate>.log, output_<raceId>.txt, diagnostics_<raceId>.txt
+		loc, err := i18n.New(config.Lang)
+		if err != nil {
+			log.Fatalf("race(%s): %v", config.RaceId, err)
+		}
+		if err := cmptmgr.ValidateRules(config.Rules); err != nil {
+			log.Fatalf("race(%s): %v", config.RaceId, loc.Localize(err))
+		}
+		if err := cmptmgr.RegisterCloseEvent(config.CloseEventId); err != nil {
+			log.Fatalf("race(%s): %v", config.RaceId, err)
+		}
+		logFile, err := openRaceLog(config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer logFile.Close()
+		outFile, err := os.OpenFile("output_"+config.RaceId+".txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer outFile.Close()
+		diagFile, err := os.OpenFile("diagnostics_"+config.RaceId+".txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer diagFile.Close()
+		diagFiles[config.RaceId] = diagFile
+		locs[config.RaceId] = loc
+
+		cmptMgr := cmptmgr.NewCompetitionManager(outFile, config)
+		cmptMgr.AddObserver(cl.NewCustomLogger(logFile, cl.WithLocalizer(loc)))
+		if _, err := registry.Add(config, cmptMgr); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	parser := cl.NewParser(format)
+	closed := make(map[string]bool) //О закрытой гонке сообщаем один раз, остальные гонки продолжаются
+	scanner := bufio.NewScanner(inputFile)
+	for lineNum := 1; scanner.Scan(); lineNum += 1 {
+		raceId, line, err := races.SplitRoute(scanner.Text())
+		if err != nil {
+			log.Fatalf("races: line %d: %v", lineNum, err)
+		}
+		eventInfo, err := parser.ParseLine(line)
+		if err != nil {
+			log.Fatalf("Parser(ParseLine) error: %v", locs[raceId].Localize(err))
+		}
+		err = registry.HandleEvent(raceId, eventInfo)
+		if errors.Is(err, cmptmgr.ErrRaceClosed) {
+			if !closed[raceId] {
+				log.Printf("race(%s): %v, the rest of its events is ignored", raceId, err)
+				closed[raceId] = true
+			}
+			continue
+		}
+		if err != nil {
+			log.Fatalf("races: line %d: %v", lineNum, locs[raceId].Localize(err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := registry.Close(); err != nil { //Итоговые отчёты всех гонок, которые не закрылись раньше
+		log.Fatalf("Registry(Close) error: %v", err)
+	}
+	for _, race := range registry.Races() {
+		var err error
+		if loopErr := race.Loop.Do(func(cm *cmptmgr.CompetitionManager) { err = cm.WriteDiagnostics(diagFiles[race.Id]) }); loopErr != nil {
+			err = loopErr
+		}
+		if err != nil {
+			log.Fatalf("race(%s): CompetitorManager(WriteDiagnostics) error: %v", race.Id, err)
+		}
 	}
 }
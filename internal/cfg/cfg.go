@@ -1,6 +1,7 @@
 package cfg
 
 import (
+	"fmt"
 	"log"
 	"os"
 
@@ -14,18 +15,50 @@ type Config struct {
 	FiringLines int    `json:"firingLines" env-default:"2"`
 	Start       string `json:"start" env-default:"10:00:00.000"`
 	StartDelta  string `json:"startDelta" env-default:"00:01:30"`
+	Lang        string `json:"lang" env-default:"en"` //Язык логов, отчёта и ошибок: en или ru
+
+	RaceId     string `json:"raceId" env-default:"race"` //Из id и даты гонки собирается имя лог-файла
+	RaceDate   string `json:"raceDate"`                  //Дата гонки в формате 2006-01-02, по умолчанию сегодняшняя
+	LogDir     string `json:"logDir" env-default:"logs"`
+	MaxLogSize int64  `json:"maxLogSize"` //Размер лог-файла в байтах, после которого он ротируется(0 - без ротации)
+
+	ClockOffsets map[string]string `json:"clockOffsets"` //Поправки часов устройств хронометража по имени файла, например {"range_events": "-300ms"}
+	SyncEventId  int               `json:"syncEventId"`  //Событие синхронизации, по которому поправки оцениваются автоматически(0 - выключено)
+
+	MinLapSpeed     float64 `json:"minLapSpeed"` //Границы правдоподобной скорости в м/с, 0 - граница не проверяется
+	MaxLapSpeed     float64 `json:"maxLapSpeed"`
+	MinPenaltySpeed float64 `json:"minPenaltySpeed"`
+	MaxPenaltySpeed float64 `json:"maxPenaltySpeed"`
+
+	PenaltyLoopPerPair bool              `json:"penaltyLoopPerPair"` //Каждый штрафной круг отмечается своей парой 8/9, иначе одна пара покрывает все круги после рубежа
+	Rules              map[string]string `json:"rules"`              //Статус(DNS, DNF, DSQ, LAP или off) для правил снятия с гонки, например {"skippedPenalty": "DSQ"}
+	MaxRaceTime        string            `json:"maxRaceTime"`        //Лимит времени на дистанции для правила cutoff, пусто - без лимита
+	CutoffTime         string            `json:"cutoffTime"`         //Время закрытия трассы(hh:mm:ss.mmm), после него все, кто не финишировал, классифицируются по правилу cutoff
+
+	CloseTime        string `json:"closeTime"`        //Время(hh:mm:ss.mmm), в которое гонка закрывается и пишется итоговый отчёт, пусто - не закрывается по времени
+	CloseEventId     int    `json:"closeEventId"`     //Событие оператора "гонка закрыта"(встроенное - 12), 0 - выключено
+	CloseWhenAllDone bool   `json:"closeWhenAllDone"` //Закрыть гонку, как только у всех участников окончательный статус
 }
 
+const DefaultPath = "../internal/cfg/config.json"
+
 func MustLoad() *Config {
-	const cfg_path = "../internal/cfg/config.json"
-	if _, err := os.Stat(cfg_path); err != nil {
-		log.Fatalf("cfg not found in %s", cfg_path)
+	cfg, err := Load(DefaultPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
+
+func Load(path string) (*Config, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("cfg not found in %s", path)
 	}
 
 	cfg := &Config{}
-	if err := cleanenv.ReadConfig(cfg_path, cfg); err != nil {
-		log.Fatalf("failed to read config %s", cfg_path)
+	if err := cleanenv.ReadConfig(path, cfg); err != nil {
+		return nil, fmt.Errorf("failed to read config %s", path)
 	}
 
-	return cfg
+	return cfg, nil
 }
@@ -0,0 +1,135 @@
+package competitionmgr
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	timeParser "yadro_test/common"
+	"yadro_test/internal/i18n"
+)
+
+const SectionAdjustments = "adjustments"
+
+type Adjustment struct { //Решение жюри по времени участника, например {"competitor":2,"delta":"+00:02:00","reason":"false start"}
+	CompetitorId int           `json:"competitor"`
+	Delta        time.Duration `json:"-"` //Штраф со знаком +, зачёт времени со знаком -
+	Reason       string        `json:"reason"`
+}
+
+func (a *Adjustment) UnmarshalJSON(data []byte) error {
+	type plain Adjustment
+	raw := struct {
+		*plain
+		Delta string `json:"delta"`
+	}{plain: (*plain)(a)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	delta, err := ParseDelta(raw.Delta)
+	if err != nil {
+		return err
+	}
+	a.Delta = delta
+	return nil
+}
+
+func ParseDelta(s string) (time.Duration, error) { //+hh:mm:ss или -hh:mm:ss
+	if len(s) < 2 || (s[0] != '+' && s[0] != '-') {
+		return 0, i18n.Errorf("err.deltaSign", s)
+	}
+	dur, err := timeParser.ConvertStringToDuration(s[1:])
+	if err != nil {
+		return 0, err
+	}
+	if s[0] == '-' {
+		dur = -dur
+	}
+	return dur, nil
+}
+
+func formatDelta(d time.Duration) string {
+	if d < 0 {
+		return "-" + timeParser.ConvertDurationToString(-d)
+	}
+	return "+" + timeParser.ConvertDurationToString(d)
+}
+
+func ParseAdjustments(r io.Reader) ([]Adjustment, error) {
+	var adjustments []Adjustment
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum += 1 {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var a Adjustment
+		if err := json.Unmarshal([]byte(line), &a); err != nil {
+			return nil, i18n.Errorf("err.invalidAdjustment", lineNum, err)
+		}
+		if a.Reason == "" { //Решение жюри без причины в протокол не попадёт
+			return nil, i18n.Errorf("err.adjustmentNoReason", lineNum)
+		}
+		adjustments = append(adjustments, a)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, i18n.Errorf("err.readAdjustments", err)
+	}
+	return adjustments, nil
+}
+
+func (cm *CompetitionManager) SetAdjustments(adjustments []Adjustment) { //Участники могут зарегистрироваться позже, поэтому поправки учитываются только при финализации и в отчёте
+	cm.adjustments = adjustments
+}
+
+func (cm *CompetitionManager) adjustment(competitorId int) time.Duration {
+	var sum time.Duration
+	for _, a := range cm.adjustments {
+		if a.CompetitorId == competitorId {
+			sum += a.Delta
+		}
+	}
+	return sum
+}
+
+func (cm *CompetitionManager) resultTime(c *Competitor) time.Duration { //Итоговое время с учётом решений жюри, только у финишировавших
+	if c.Status != StatusFinished {
+		return c.TotalTime
+	}
+	return c.TotalTime + cm.adjustment(c.CompetitorId)
+}
+
+func (cm *CompetitionManager) checkAdjustments() {
+	for _, a := range cm.adjustments {
+		c, ok := cm.competitors[a.CompetitorId]
+		switch {
+		case !ok:
+			cm.addDiagnostic(Warning, SectionAdjustments, cm.lastEventTime, a.CompetitorId, "diag.unknownAdjustment", formatDelta(a.Delta))
+		case c.Status != StatusFinished:
+			cm.addDiagnostic(Warning, SectionAdjustments, cm.lastEventTime, a.CompetitorId, "diag.ignoredAdjustment", formatDelta(a.Delta), cm.loc.T("status."+c.Status.String()))
+		}
+	}
+}
+
+func (cm *CompetitionManager) formatAdjustment(c *Competitor) string {
+	if d := cm.resultTime(c) - c.TotalTime; d != 0 { //Не финишировавшим поправки не применяются
+		return formatDelta(d)
+	}
+	return "-"
+}
+
+func (cm *CompetitionManager) writeAdjustments() error {
+	if len(cm.adjustments) == 0 {
+		return nil
+	}
+	lines := []string{cm.loc.T("report.adjustments")}
+	for i, a := range cm.adjustments {
+		lines = append(lines, cm.loc.T("report.adjustment", i+1, a.CompetitorId, formatDelta(a.Delta), a.Reason))
+	}
+	if _, err := io.WriteString(cm.output, strings.Join(lines, "\n")+"\n"); err != nil {
+		return cm.loc.Errorf("err.writeReport", err)
+	}
+	return nil
+}
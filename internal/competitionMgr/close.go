@@ -0,0 +1,100 @@
+package competitionmgr
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	timeParser "yadro_test/common"
+	lh "yadro_test/internal/logger"
+)
+
+const EventRaceClosed = 12 //Оператор закрывает гонку: [11:30:00.000] 12 0
+
+var ErrRaceClosed = errors.New("race is closed")
+
+const raceClosedName = "race closed"
+
+func RegisterCloseEvent(eventId int) error { //closeEventId из конфига должен знать и парсер, иначе событие отклоняется как неизвестное
+	if eventId == 0 {
+		return nil
+	}
+	if t, ok := lh.LookupEventType(eventId); ok {
+		if t.Name == raceClosedName { //Встроенное событие 12 или уже зарегистрировано для другой гонки
+			return nil
+		}
+		return fmt.Errorf("closeEventId(%d) is already used by event %q", eventId, t.Name)
+	}
+	return lh.RegisterEventType(lh.EventType{Id: eventId, Name: raceClosedName, Template: "The race was closed", Key: fmt.Sprintf("event.%d", EventRaceClosed)})
+}
+
+type raceClosedError struct { //Локализованный текст, но errors.Is(err, ErrRaceClosed) по-прежнему работает
+	msg string
+}
+
+func (e raceClosedError) Error() string {
+	return e.msg
+}
+
+func (e raceClosedError) Is(target error) bool {
+	return target == ErrRaceClosed
+}
+
+func (cm *CompetitionManager) Closed() bool {
+	return cm.closed
+}
+
+func (cm *CompetitionManager) raceClosedErr() error {
+	return raceClosedError{cm.loc.T("err.raceClosed", cm.closedAt.Format("15:04:05.000"))}
+}
+
+func (cm *CompetitionManager) Close() error { //Закрыть гонку вручную или по концу входного потока, повторный вызов ничего не делает
+	return cm.closeAt(cm.lastEventTime)
+}
+
+func (cm *CompetitionManager) closeAt(at time.Time) error { //Финализируем статусы и пишем итоговый отчёт, дальше события не принимаются
+	if cm.closed {
+		return nil
+	}
+	cm.closed = true
+	cm.closedAt = at
+	if at.After(cm.lastEventTime) {
+		cm.lastEventTime = at
+	}
+	cm.Finalize()
+	if cm.replaying { //Отчёт уже был записан до перезапуска
+		return nil
+	}
+	return cm.GenerateReport()
+}
+
+func (cm *CompetitionManager) checkCloseTime(eventTime time.Time) (bool, error) { //Событие позже closeTime закрывает гонку и само уже не принимается
+	if cm.cfg.CloseTime == "" {
+		return false, nil
+	}
+	closeTime, err := timeParser.ConvertStringToTime(cm.cfg.CloseTime)
+	if err != nil {
+		return false, err
+	}
+	if !eventTime.After(closeTime) {
+		return false, nil
+	}
+	return true, cm.closeAt(closeTime)
+}
+
+func (cm *CompetitionManager) allTerminal() bool {
+	if len(cm.competitors) == 0 {
+		return false
+	}
+	for _, c := range cm.competitors {
+		if !c.Status.Terminal() {
+			return false
+		}
+	}
+	return true
+}
+
+func (cm *CompetitionManager) handleRaceClosed(eventInfo lh.EventInfo) error {
+	cm.notify(eventInfo) //Само событие закрытия должно попасть в лог раньше итоговых статусов
+	return cm.closeAt(eventInfo.EventTime)
+}
@@ -1,29 +1,31 @@
 package competitionmgr
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
-	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"yadro_test/internal/cfg"
+	"yadro_test/internal/corrections"
+	"yadro_test/internal/i18n"
 	lh "yadro_test/internal/logger"
 )
 
 func TestHandleEventRegistration(t *testing.T) {
-	tmpfile, err := os.CreateTemp("", "testoutput")
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-	defer os.Remove(tmpfile.Name())
-	defer tmpfile.Close()
+	out := new(bytes.Buffer)
 
 	cfg := &cfg.Config{FiringLines: 2}
-	cm := NewCompetitionManager(tmpfile, cfg)
+	cm := NewCompetitionManager(out, cfg)
 
 	// Event 1: Registration
-	err = cm.HandleEvent(lh.EventInfo{
+	err := cm.HandleEvent(lh.EventInfo{
 		EventId:      1,
 		CompetitorId: 100,
 		EventTime:    time.Now(),
@@ -44,17 +46,12 @@ func TestHandleEventRegistration(t *testing.T) {
 }
 
 func TestHandleEventStartTime(t *testing.T) {
-	tmpfile, err := os.CreateTemp("", "testoutput")
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-	defer os.Remove(tmpfile.Name())
-	defer tmpfile.Close()
+	out := new(bytes.Buffer)
 
 	cfg := &cfg.Config{FiringLines: 2}
-	cm := NewCompetitionManager(tmpfile, cfg)
+	cm := NewCompetitionManager(out, cfg)
 
-	err = cm.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 100})
+	err := cm.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 100})
 	if err != nil {
 		t.Fatalf("HandleEvent failed: %v", err)
 	}
@@ -88,22 +85,17 @@ func TestHandleEventStartTime(t *testing.T) {
 }
 
 func TestHandleEventLapCompletion(t *testing.T) {
-	tmpfile, err := os.CreateTemp("", "testoutput")
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-	defer os.Remove(tmpfile.Name())
-	defer tmpfile.Close()
+	out := new(bytes.Buffer)
 
 	cfg := &cfg.Config{
 		Laps:       1,
 		LapLen:     1000,
 		StartDelta: "00:03:00",
 	}
-	cm := NewCompetitionManager(tmpfile, cfg)
+	cm := NewCompetitionManager(out, cfg)
 
 	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
-	err = cm.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 100})
+	err := cm.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 100})
 	if err != nil {
 		t.Fatalf("HandleEvent failed: %v", err)
 	}
@@ -144,7 +136,7 @@ func TestHandleEventLapCompletion(t *testing.T) {
 	if cm.competitors[100].LapsEnded != 1 {
 		t.Errorf("Expected 1 lap ended, got %d", cm.competitors[100].LapsEnded)
 	}
-	if cm.competitors[100].Status != "Finished" {
+	if cm.competitors[100].Status != StatusFinished {
 		t.Errorf("Expected status Finished, got %s", cm.competitors[100].Status)
 	}
 	if cm.competitors[100].TotalTime != 6*time.Minute {
@@ -153,22 +145,17 @@ func TestHandleEventLapCompletion(t *testing.T) {
 }
 
 func TestHandleEventCompetitionFailing(t *testing.T) {
-	tmpfile, err := os.CreateTemp("", "testoutput")
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-	defer os.Remove(tmpfile.Name())
-	defer tmpfile.Close()
+	out := new(bytes.Buffer)
 
 	cfg := &cfg.Config{
 		Laps:       1,
 		LapLen:     1000,
 		StartDelta: "00:02:00",
 	}
-	cm := NewCompetitionManager(tmpfile, cfg)
+	cm := NewCompetitionManager(out, cfg)
 
 	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
-	err = cm.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 100})
+	err := cm.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 100})
 	if err != nil {
 		t.Fatalf("HandleEvent failed: %v", err)
 	}
@@ -214,8 +201,8 @@ func TestHandleEventCompetitionFailing(t *testing.T) {
 	if cm.competitors[100].LapsEnded != 0 {
 		t.Errorf("Expected 0 lap ended, got %d", cm.competitors[100].LapsEnded)
 	}
-	if cm.competitors[100].Status != "NotFinished" {
-		t.Errorf("Expected status NotFinished, got %s", cm.competitors[100].Status)
+	if cm.competitors[100].Status != StatusDNF {
+		t.Errorf("Expected status DNF, got %s", cm.competitors[100].Status)
 	}
 	if cm.competitors[100].TotalTime != 6*time.Minute {
 		t.Errorf("Expected total time 6m, got %v", cm.competitors[100].TotalTime)
@@ -304,3 +291,1335 @@ func TestCountHits(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleEventDuplicates(t *testing.T) {
+	out := new(bytes.Buffer)
+
+	cfg := &cfg.Config{Laps: 1, FiringLines: 1}
+	cm := NewCompetitionManager(out, cfg)
+
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 100, EventTime: startTime},
+		{EventId: 1, CompetitorId: 100, EventTime: startTime}, //Точный дубликат
+		{EventId: 2, CompetitorId: 100, ExtraParams: "12:01:00.000", EventTime: startTime},
+		{EventId: 1, CompetitorId: 100, EventTime: startTime.Add(time.Second)},                              //Повторная регистрация
+		{EventId: 2, CompetitorId: 100, ExtraParams: "12:01:00.000", EventTime: startTime.Add(time.Second)}, //Повторная жеребьёвка с тем же временем
+		{EventId: 2, CompetitorId: 100, ExtraParams: "12:05:00.000", EventTime: startTime.Add(time.Minute)}, //Конфликтующая жеребьёвка
+	}
+	for _, e := range events {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+
+	if got := cm.competitors[100].StartTime.Format("15:04:05.000"); got != "12:01:00.000" {
+		t.Errorf("Expected StartTime 12:01:00.000 to be kept, got %s", got)
+	}
+
+	diags := cm.Diagnostics()
+	if len(diags) != 4 {
+		t.Fatalf("Expected 4 diagnostics, got %d: %v", len(diags), diags)
+	}
+	for i, want := range []struct {
+		severity Severity
+		section  string
+	}{
+		{Warning, SectionDuplicates},
+		{Warning, SectionDuplicates},
+		{Warning, SectionDuplicates},
+		{Error, SectionConflicts},
+	} {
+		if diags[i].Severity != want.severity || diags[i].Section != want.section {
+			t.Errorf("diagnostic %d = %v/%s, want %v/%s", i, diags[i].Severity, diags[i].Section, want.severity, want.section)
+		}
+	}
+}
+
+func TestHandleEventUnregisteredCompetitor(t *testing.T) {
+	out := new(bytes.Buffer)
+
+	cm := NewCompetitionManager(out, &cfg.Config{})
+	err := cm.HandleEvent(lh.EventInfo{EventId: 4, CompetitorId: 7})
+	if err == nil || err.Error() != "competitor(7) is not registered" {
+		t.Errorf("HandleEvent() error = %v, want competitor(7) is not registered", err)
+	}
+}
+
+func TestHandleEventHitPayload(t *testing.T) {
+	out := new(bytes.Buffer)
+
+	cm := NewCompetitionManager(out, &cfg.Config{FiringLines: 1})
+	if err := cm.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 100}); err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+
+	err := cm.HandleEvent(lh.EventInfo{EventId: 6, CompetitorId: 100, ExtraParams: "3", Payload: lh.HitEvent{Target: 3}})
+	if err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if !cm.competitors[100].Hits[2] {
+		t.Errorf("Expected target 3 to be hit, got %v", cm.competitors[100].Hits)
+	}
+
+	err = cm.HandleEvent(lh.EventInfo{EventId: 6, CompetitorId: 100, ExtraParams: "7"})
+	if err == nil {
+		t.Error("HandleEvent expected error for invalid target, got nil")
+	}
+
+	cm.competitors[100].FiringRangeNum = 1 //Второй рубеж при FiringLines=1 - мишени за пределами конфига
+	err = cm.HandleEvent(lh.EventInfo{EventId: 6, CompetitorId: 100, Payload: lh.HitEvent{Target: 1}})
+	if err == nil {
+		t.Error("HandleEvent expected error for target beyond configured firing lines, got nil")
+	}
+}
+
+func TestRegisterEvent(t *testing.T) {
+	t.Cleanup(func() {
+		delete(eventHandlers, 201)
+		lh.UnregisterEventType(201)
+	})
+	out := new(bytes.Buffer)
+
+	checked := make(map[int]bool)
+	err := RegisterEvent(EventDef{
+		EventType: lh.EventType{Id: 201, Name: "equipment check", Template: "The equipment of competitor({competitor}) was checked"},
+		Handle: func(cm *CompetitionManager, c *Competitor, e lh.EventInfo) error {
+			checked[c.CompetitorId] = true
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterEvent() error = %v", err)
+	}
+	if err := RegisterEvent(EventDef{EventType: lh.EventType{Id: 202, Template: "no handler"}}); err == nil {
+		t.Error("RegisterEvent() expected error for missing handler")
+	}
+
+	cm := NewCompetitionManager(out, &cfg.Config{})
+	if err := cm.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 100}); err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if err := cm.HandleEvent(lh.EventInfo{EventId: 201, CompetitorId: 100}); err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if !checked[100] {
+		t.Error("Custom handler was not called")
+	}
+
+	err = cm.HandleEvent(lh.EventInfo{EventId: 99, CompetitorId: 100})
+	if err == nil || err.Error() != "unknown event id(99)" {
+		t.Errorf("HandleEvent() error = %v, want unknown event id(99)", err)
+	}
+}
+
+func TestGenerateReportRussian(t *testing.T) {
+	out := new(bytes.Buffer)
+
+	cm := NewCompetitionManager(out, &cfg.Config{Laps: 1, FiringLines: 1, Lang: "ru"})
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 100},
+		{EventId: 2, CompetitorId: 100, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 11, CompetitorId: 100, ExtraParams: "Lost", EventTime: startTime.Add(time.Minute)},
+	}
+	for _, e := range events {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+	if err := cm.GenerateReport(); err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+
+	want := "[НеФинишировал] 100 [{,}] {,} 0/0 retired\n"
+	if out.String() != want {
+		t.Errorf("GenerateReport() = %q, want %q", out.String(), want)
+	}
+
+	err := cm.HandleEvent(lh.EventInfo{EventId: 4, CompetitorId: 7})
+	if err == nil || err.Error() != "участник(7) не зарегистрирован" {
+		t.Errorf("HandleEvent() error = %v, want localized error", err)
+	}
+}
+
+type recordingObserver struct {
+	events []lh.EventInfo
+}
+
+func (o *recordingObserver) LogEvent(eventInfo lh.EventInfo) {
+	o.events = append(o.events, eventInfo)
+}
+
+func TestObserversSeeOnlyAcceptedEvents(t *testing.T) {
+	cm := NewCompetitionManager(new(bytes.Buffer), &cfg.Config{Laps: 1, FiringLines: 1, StartDelta: "00:01:30"})
+	observer := &recordingObserver{}
+	cm.AddObserver(observer)
+
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 100, EventTime: startTime},
+		{EventId: 1, CompetitorId: 100, EventTime: startTime}, //Дубликат - в лог не попадёт
+		{EventId: 2, CompetitorId: 100, ExtraParams: "12:01:00.000", EventTime: startTime},
+		{EventId: 2, CompetitorId: 100, ExtraParams: "12:05:00.000", EventTime: startTime}, //Конфликт - в лог не попадёт
+		{EventId: 4, CompetitorId: 100, EventTime: startTime.Add(time.Minute)},
+		{EventId: 10, CompetitorId: 100, EventTime: startTime.Add(10 * time.Minute)},
+	}
+	for _, e := range events {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+	if err := cm.HandleEvent(lh.EventInfo{EventId: 6, CompetitorId: 100, ExtraParams: "9"}); err == nil {
+		t.Fatal("HandleEvent expected error for invalid target")
+	}
+
+	var got []int
+	for _, e := range observer.events {
+		got = append(got, e.EventId)
+	}
+	want := []int{1, 2, 4, 10, EventFinished}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Observed events = %v, want %v", got, want)
+	}
+
+	lapEnd := observer.events[3].State //Наблюдатели получают состояние участника уже после применения события
+	if lapEnd == nil || lapEnd.Status != "Finished" || lapEnd.LapsEnded != 1 || lapEnd.LapTime != 9*time.Minute || lapEnd.TotalTime != 9*time.Minute {
+		t.Errorf("Lap end state = %+v, want Finished after 1 lap", lapEnd)
+	}
+	if start := observer.events[2].State; start == nil || start.Status != "Started" || start.LapsEnded != 0 {
+		t.Errorf("Start state = %+v, want Started", start)
+	}
+}
+
+func TestPlausibilityChecks(t *testing.T) {
+	out := new(bytes.Buffer)
+
+	cm := NewCompetitionManager(out, &cfg.Config{
+		Laps: 1, LapLen: 3500, PenaltyLen: 150, FiringLines: 1, StartDelta: "00:01:30",
+		MinLapSpeed: 1, MaxLapSpeed: 12, MinPenaltySpeed: 0.5, MaxPenaltySpeed: 12,
+	})
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 100, EventTime: startTime},
+		{EventId: 2, CompetitorId: 100, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 100, EventTime: startTime.Add(10 * time.Second)},
+		{EventId: 5, CompetitorId: 100, ExtraParams: "1", EventTime: startTime.Add(30 * time.Second)},
+		{EventId: 6, CompetitorId: 100, ExtraParams: "1", EventTime: startTime.Add(31 * time.Second)},
+		{EventId: 6, CompetitorId: 100, ExtraParams: "2", EventTime: startTime.Add(32 * time.Second)},
+		{EventId: 6, CompetitorId: 100, ExtraParams: "3", EventTime: startTime.Add(33 * time.Second)},
+		{EventId: 6, CompetitorId: 100, ExtraParams: "4", EventTime: startTime.Add(34 * time.Second)},
+		{EventId: 7, CompetitorId: 100, EventTime: startTime.Add(40 * time.Second)},
+		{EventId: 8, CompetitorId: 100, EventTime: startTime.Add(time.Minute)},
+		{EventId: 9, CompetitorId: 100, EventTime: startTime.Add(time.Minute + 5*time.Second)}, //150 м за 5 секунд - потеряна отметка
+		{EventId: 10, CompetitorId: 100, EventTime: startTime.Add(4 * time.Minute)},            //3500 м за 4 минуты
+	}
+	for _, e := range events {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+
+	diags := cm.Diagnostics()
+	if len(diags) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d: %v", len(diags), diags)
+	}
+	for i, want := range []string{
+		"impossible penalty laps speed 30.000 m/s after firing range 1(allowed 0.500-12.000)",
+		"impossible speed 14.583 m/s on lap 1(allowed 1.000-12.000)",
+	} {
+		if diags[i].Section != SectionPlausibility || diags[i].Severity != Error || !strings.HasPrefix(diags[i].Message, want) {
+			t.Errorf("diagnostic %d = %+v, want plausibility error %q", i, diags[i], want)
+		}
+	}
+
+	if err := cm.GenerateReport(); err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+	want := "[00:04:00.000] 100 [{00:04:00.000, 14.583!}] {00:00:05.000, 30.000!} 4/5\n"
+	if out.String() != want {
+		t.Errorf("GenerateReport() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestSpeedBounds(t *testing.T) {
+	tests := []struct {
+		bounds speedBounds
+		speed  float64
+		want   bool
+	}{
+		{speedBounds{}, 100, true},
+		{speedBounds{1, 12}, 4.6, true},
+		{speedBounds{1, 12}, 12.5, false},
+		{speedBounds{1, 12}, 0.5, false},
+		{speedBounds{0, 12}, 0, true},
+		{speedBounds{1, 0}, 40, true},
+	}
+	for _, tt := range tests {
+		if got := tt.bounds.contains(tt.speed); got != tt.want {
+			t.Errorf("%v.contains(%v) = %v, want %v", tt.bounds, tt.speed, got, tt.want)
+		}
+	}
+}
+
+func TestPenaltyLoopsAgainstMisses(t *testing.T) {
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := startTime
+	next := func() time.Time { //Каждое событие на секунду позже предыдущего, чтобы не попасть в дубликаты
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	shooting := func(rangeNum string, hits ...string) func() []lh.EventInfo { //Рубеж с заданными попаданиями
+		return func() []lh.EventInfo {
+			events := []lh.EventInfo{{EventId: 5, CompetitorId: 100, ExtraParams: rangeNum, EventTime: next()}}
+			for _, h := range hits {
+				events = append(events, lh.EventInfo{EventId: 6, CompetitorId: 100, ExtraParams: h, EventTime: next()})
+			}
+			return append(events, lh.EventInfo{EventId: 7, CompetitorId: 100, EventTime: next()})
+		}
+	}
+	penalty := func() []lh.EventInfo {
+		return []lh.EventInfo{
+			{EventId: 8, CompetitorId: 100, EventTime: next()},
+			{EventId: 9, CompetitorId: 100, EventTime: next()},
+		}
+	}
+	penaltyLoop := func() []lh.EventInfo { //Один штрафной круг 150 м за 25 секунд - 6 м/с
+		enter := next()
+		clock = enter.Add(25 * time.Second)
+		return []lh.EventInfo{
+			{EventId: 8, CompetitorId: 100, EventTime: enter},
+			{EventId: 9, CompetitorId: 100, EventTime: clock},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		perPair         bool
+		maxPenaltySpeed float64
+		events          []func() []lh.EventInfo
+		wantSev         []Severity
+		wantSkipped     int
+	}{
+		{"one pair covers all misses", false, 0, []func() []lh.EventInfo{shooting("1", "1", "2", "3"), penalty, shooting("2", "1", "2", "3", "4", "5")}, nil, 0},
+		{"penalty skipped", false, 0, []func() []lh.EventInfo{shooting("1", "1", "2", "3"), shooting("2", "1", "2", "3", "4", "5")}, []Severity{Error}, 2},
+		{"penalty without misses", false, 0, []func() []lh.EventInfo{shooting("1", "1", "2", "3", "4", "5"), penalty, shooting("2", "1", "2", "3", "4", "5")}, []Severity{Warning}, 0},
+		{"pair per loop, one skipped", true, 0, []func() []lh.EventInfo{shooting("1", "1", "2", "3"), penalty, shooting("2", "1", "2", "3", "4", "5")}, []Severity{Error}, 1},
+		{"pair per loop, extra on last range", true, 0, []func() []lh.EventInfo{shooting("1", "1", "2", "3", "4", "5"), shooting("2", "1", "2", "3", "4"), penalty, penalty}, []Severity{Warning}, 0},
+		{"pair per loop, speed of one loop", true, 10, []func() []lh.EventInfo{shooting("1", "1", "2", "3", "4", "5"), shooting("2"), penaltyLoop, penaltyLoop, penaltyLoop, penaltyLoop, penaltyLoop}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := NewCompetitionManager(new(bytes.Buffer), &cfg.Config{
+				Laps: 1, LapLen: 3500, PenaltyLen: 150, FiringLines: 2, StartDelta: "00:01:30", PenaltyLoopPerPair: tt.perPair,
+				MaxPenaltySpeed: tt.maxPenaltySpeed,
+			})
+			clock = startTime
+			events := []lh.EventInfo{
+				{EventId: 1, CompetitorId: 100, EventTime: startTime},
+				{EventId: 2, CompetitorId: 100, ExtraParams: "12:00:00.000", EventTime: startTime},
+				{EventId: 4, CompetitorId: 100, EventTime: next()},
+			}
+			for _, part := range tt.events {
+				events = append(events, part()...)
+			}
+			events = append(events, lh.EventInfo{EventId: 10, CompetitorId: 100, EventTime: clock.Add(15 * time.Minute)})
+			for _, e := range events {
+				if err := cm.HandleEvent(e); err != nil {
+					t.Fatalf("HandleEvent failed: %v", err)
+				}
+			}
+
+			diags := cm.Diagnostics()
+			if len(diags) != len(tt.wantSev) {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", len(tt.wantSev), len(diags), diags)
+			}
+			for i, sev := range tt.wantSev {
+				if diags[i].Severity != sev || diags[i].Section != SectionPenalties {
+					t.Errorf("diagnostic %d = %+v, want %v in %s", i, diags[i], sev, SectionPenalties)
+				}
+			}
+			if got := cm.competitors[100].PenaltySkipped; got != tt.wantSkipped {
+				t.Errorf("PenaltySkipped = %d, want %d", got, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return startTime.Add(d) }
+	registered := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 100, EventTime: startTime},
+		{EventId: 2, CompetitorId: 100, ExtraParams: "12:00:00.000", EventTime: startTime},
+	}
+	shooting := []lh.EventInfo{
+		{EventId: 5, CompetitorId: 100, ExtraParams: "1", EventTime: at(2 * time.Minute)},
+		{EventId: 6, CompetitorId: 100, ExtraParams: "1", EventTime: at(2*time.Minute + time.Second)},
+		{EventId: 7, CompetitorId: 100, EventTime: at(3 * time.Minute)},
+	}
+
+	tests := []struct {
+		name        string
+		rules       map[string]string
+		maxRaceTime string
+		events      []lh.EventInfo
+		wantStatus  Status
+		wantReason  string
+		wantTotal   time.Duration
+		wantEmitted string //ExtraParams исходящего события 32, пусто - события нет
+	}{
+		{"late start is DNS by default", nil, "", []lh.EventInfo{
+			{EventId: 4, CompetitorId: 100, EventTime: at(5 * time.Minute)},
+			{EventId: 10, CompetitorId: 100, EventTime: at(20 * time.Minute)},
+		}, StatusDNS, "startWindow", 90 * time.Second, "DNS startWindow"},
+		{"start window rule switched off", map[string]string{"startWindow": "off"}, "", []lh.EventInfo{
+			{EventId: 4, CompetitorId: 100, EventTime: at(5 * time.Minute)},
+			{EventId: 10, CompetitorId: 100, EventTime: at(20 * time.Minute)},
+		}, StatusFinished, "", 20 * time.Minute, ""},
+		{"missed firing range", map[string]string{"missedRange": "DSQ"}, "", []lh.EventInfo{
+			{EventId: 4, CompetitorId: 100, EventTime: at(time.Second)},
+			{EventId: 10, CompetitorId: 100, EventTime: at(20 * time.Minute)},
+		}, StatusDSQ, "missedRange", 20 * time.Minute, "DSQ missedRange"},
+		{"skipped penalty laps", map[string]string{"skippedPenalty": "DSQ"}, "", append(append([]lh.EventInfo{
+			{EventId: 4, CompetitorId: 100, EventTime: at(time.Second)},
+		}, shooting...), lh.EventInfo{EventId: 10, CompetitorId: 100, EventTime: at(20 * time.Minute)}),
+			StatusDSQ, "skippedPenalty", 20 * time.Minute, "DSQ skippedPenalty"},
+		{"over the cutoff", map[string]string{"cutoff": "DNF"}, "00:15:00", []lh.EventInfo{
+			{EventId: 4, CompetitorId: 100, EventTime: at(time.Second)},
+			{EventId: 10, CompetitorId: 100, EventTime: at(20 * time.Minute)},
+		}, StatusDNF, "cutoff", 20 * time.Minute, "DNF cutoff"},
+		{"too few laps", map[string]string{"tooFewLaps": "DNF"}, "", []lh.EventInfo{
+			{EventId: 4, CompetitorId: 100, EventTime: at(time.Second)},
+			{EventId: 3, CompetitorId: 100, EventTime: at(10 * time.Minute)},
+		}, StatusDNF, "tooFewLaps", 10 * time.Minute, "DNF tooFewLaps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := NewCompetitionManager(new(bytes.Buffer), &cfg.Config{
+				Laps: 1, LapLen: 3500, PenaltyLen: 150, FiringLines: 1, StartDelta: "00:01:30", Rules: tt.rules, MaxRaceTime: tt.maxRaceTime,
+			})
+			observer := &recordingObserver{}
+			cm.AddObserver(observer)
+			for _, e := range append(append([]lh.EventInfo{}, registered...), tt.events...) {
+				if err := cm.HandleEvent(e); err != nil {
+					t.Fatalf("HandleEvent failed: %v", err)
+				}
+			}
+			cm.Finalize()
+
+			c := cm.competitors[100]
+			if c.Status != tt.wantStatus || c.Reason != tt.wantReason || c.TotalTime != tt.wantTotal {
+				t.Errorf("Got %s/%s/%v, want %s/%s/%v", c.Status, c.Reason, c.TotalTime, tt.wantStatus, tt.wantReason, tt.wantTotal)
+			}
+			emitted := ""
+			for _, e := range observer.events {
+				if e.EventId == EventDisqualified {
+					emitted = e.ExtraParams
+				}
+			}
+			if emitted != tt.wantEmitted {
+				t.Errorf("Event %d extra = %q, want %q", EventDisqualified, emitted, tt.wantEmitted)
+			}
+		})
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		rules   map[string]string
+		wantErr bool
+	}{
+		{nil, false},
+		{map[string]string{"skippedPenalty": "DSQ", "cutoff": "off"}, false},
+		{map[string]string{"skipedPenalty": "DSQ"}, true},
+		{map[string]string{"cutoff": "DQ"}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateRules(tt.rules); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRules(%v) error = %v, wantErr %v", tt.rules, err, tt.wantErr)
+		}
+	}
+
+	ru, _ := i18n.New("ru") //Ошибка конфига переводится на язык гонки там, где её показывают
+	err := ValidateRules(map[string]string{"cutoff": "DQ"})
+	if got, want := ru.Localize(err), "некорректный статус(DQ) для правила cutoff, ожидается DNS, DNF, DSQ, LAP или off"; got != want {
+		t.Errorf("Localize(ValidateRules()) = %q, want %q", got, want)
+	}
+}
+
+func TestLappedCompetitors(t *testing.T) {
+	out := new(bytes.Buffer)
+
+	cm := NewCompetitionManager(out, &cfg.Config{
+		Laps: 3, LapLen: 1000, FiringLines: 0, StartDelta: "00:01:30", Rules: map[string]string{"lapped": "LAP"},
+	})
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return startTime.Add(time.Duration(m) * time.Minute) }
+	events := []lh.EventInfo{}
+	for _, id := range []int{1, 2, 3} {
+		events = append(events,
+			lh.EventInfo{EventId: 1, CompetitorId: id, EventTime: startTime},
+			lh.EventInfo{EventId: 2, CompetitorId: id, ExtraParams: "12:00:00.000", EventTime: startTime},
+			lh.EventInfo{EventId: 4, CompetitorId: id, EventTime: startTime.Add(time.Duration(id) * time.Second)},
+		)
+	}
+	events = append(events,
+		lh.EventInfo{EventId: 10, CompetitorId: 1, EventTime: at(5)},
+		lh.EventInfo{EventId: 10, CompetitorId: 2, EventTime: at(8)},
+		lh.EventInfo{EventId: 10, CompetitorId: 1, EventTime: at(10)}, //Лидер на втором круге, 3 ещё не закончил первый - обойдён
+		lh.EventInfo{EventId: 10, CompetitorId: 1, EventTime: at(15)}, //Лидер финишировал, 2 прошёл только один круг - обойдён
+	)
+	for _, e := range events {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+
+	for id, want := range map[int]Status{1: StatusFinished, 2: StatusLAP, 3: StatusLAP} {
+		if got := cm.competitors[id].Status; got != want {
+			t.Errorf("Competitor %d status = %s, want %s", id, got, want)
+		}
+	}
+
+	if err := cm.GenerateReport(); err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+	var order []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		order = append(order, strings.Fields(line)[1])
+	}
+	if got := strings.Join(order, ","); got != "1,2,3" { //2 обойдён позже и с большим числом кругов - выше 3
+		t.Errorf("Report order = %s, want 1,2,3\n%s", got, out.String())
+	}
+}
+
+func TestCutoffTime(t *testing.T) {
+	cm := NewCompetitionManager(new(bytes.Buffer), &cfg.Config{
+		Laps: 1, LapLen: 1000, StartDelta: "00:01:30", CutoffTime: "12:30:00.000",
+	})
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []lh.EventInfo{}
+	for _, id := range []int{1, 2} {
+		events = append(events,
+			lh.EventInfo{EventId: 1, CompetitorId: id, EventTime: startTime},
+			lh.EventInfo{EventId: 2, CompetitorId: id, ExtraParams: "12:00:00.000", EventTime: startTime},
+			lh.EventInfo{EventId: 4, CompetitorId: id, EventTime: startTime.Add(time.Duration(id) * time.Second)},
+		)
+	}
+	events = append(events,
+		lh.EventInfo{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(20 * time.Minute)},
+		lh.EventInfo{EventId: 10, CompetitorId: 2, EventTime: startTime.Add(40 * time.Minute)}, //Уже после закрытия трассы
+	)
+	for _, e := range events {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+
+	if got := cm.competitors[1].Status; got != StatusFinished {
+		t.Errorf("Competitor 1 status = %s, want Finished", got)
+	}
+	c := cm.competitors[2]
+	if c.Status != StatusDNF || c.Reason != string(RuleCutoff) || c.TotalTime != 30*time.Minute {
+		t.Errorf("Competitor 2 = %s/%s/%v, want DNF/cutoff/30m0s", c.Status, c.Reason, c.TotalTime)
+	}
+}
+
+func TestCutoffOutgoingEvents(t *testing.T) {
+	config := &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30", CutoffTime: "12:30:00.000"}
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	setup := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime},
+		{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)},
+	}
+	after := startTime.Add(40 * time.Minute)
+	tests := []struct {
+		name    string
+		trigger func(cm *CompetitionManager) error
+	}{
+		{"clock without events", func(cm *CompetitionManager) error { return cm.AdvanceTo(after) }},
+		{"ignored event", func(cm *CompetitionManager) error { //Повторная регистрация не пишется в лог, но классификация по cutoffTime должна
+			return cm.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 1, EventTime: after})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := NewCompetitionManager(new(bytes.Buffer), config)
+			observer := &recordingObserver{}
+			cm.AddObserver(observer)
+			for _, e := range setup {
+				if err := cm.HandleEvent(e); err != nil {
+					t.Fatalf("HandleEvent failed: %v", err)
+				}
+			}
+			if err := cm.AdvanceTo(startTime.Add(20 * time.Minute)); err != nil || cm.competitors[1].Status != StatusStarted { //До cutoffTime ничего не происходит
+				t.Fatalf("AdvanceTo() before cutoff = %v, status %s", err, cm.competitors[1].Status)
+			}
+
+			if err := tt.trigger(cm); err != nil {
+				t.Fatalf("trigger failed: %v", err)
+			}
+			if c := cm.competitors[1]; c.Status != StatusDNF || c.Reason != string(RuleCutoff) {
+				t.Errorf("Competitor 1 = %s/%s, want DNF/cutoff", c.Status, c.Reason)
+			}
+			last := observer.events[len(observer.events)-1]
+			if last.EventId != EventDisqualified || last.ExtraParams != "DNF cutoff" || len(cm.pending) != 0 {
+				t.Errorf("Expected the outgoing event 32 to be logged, last event %+v, pending %d", last, len(cm.pending))
+			}
+		})
+	}
+}
+
+func TestStatusText(t *testing.T) {
+	for s := StatusRegistered; s <= StatusDNS; s++ {
+		text, err := s.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%d) error = %v", s, err)
+		}
+		var got Status
+		if err := got.UnmarshalText(text); err != nil || got != s {
+			t.Errorf("UnmarshalText(%s) = %v, %v, want %v", text, got, err, s)
+		}
+	}
+	if _, err := Status(42).MarshalText(); err == nil {
+		t.Error("MarshalText expected error for unknown status")
+	}
+	var s Status
+	if err := s.UnmarshalText([]byte("NotFinished")); err == nil {
+		t.Error("UnmarshalText expected error for unknown status")
+	}
+
+	data, err := json.Marshal(map[string]Status{"status": StatusLAP})
+	if err != nil || string(data) != `{"status":"LAP"}` {
+		t.Errorf("json.Marshal = %s, %v", data, err)
+	}
+}
+
+func TestReportRanking(t *testing.T) {
+	out := new(bytes.Buffer)
+
+	cm := NewCompetitionManager(out, &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30"})
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime}, //Только зарегистрирован
+		{EventId: 1, CompetitorId: 2, EventTime: startTime},
+		{EventId: 2, CompetitorId: 2, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 2, EventTime: startTime.Add(5 * time.Minute)}, //Опоздал на старт
+		{EventId: 1, CompetitorId: 3, EventTime: startTime},
+		{EventId: 2, CompetitorId: 3, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 3, EventTime: startTime.Add(time.Second)},
+		{EventId: 11, CompetitorId: 3, ExtraParams: "Lost", EventTime: startTime.Add(time.Minute)},
+		{EventId: 1, CompetitorId: 4, EventTime: startTime},
+		{EventId: 2, CompetitorId: 4, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 4, EventTime: startTime.Add(time.Second)},
+		{EventId: 10, CompetitorId: 4, EventTime: startTime.Add(30 * time.Minute)},
+	}
+	for _, e := range events {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+	if err := cm.GenerateReport(); err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+
+	want := "[00:30:00.000] 4 [{00:30:00.000, 0.555}] {,} 0/0\n" + //Финишировавший - первым, хоть его время и самое большое
+		"[NotFinished] 3 [{,}] {,} 0/0 retired\n" +
+		"[NotStarted] 2 [{,}] {,} 0/0 startWindow\n" +
+		"[Registered] 1 [{,}] {,} 0/0\n"
+	if out.String() != want {
+		t.Errorf("GenerateReport() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules map[string]string
+	}{
+		{"default rules", nil},
+		{"rules switched off", map[string]string{"tooFewLaps": "off", "noStart": "off"}}, //Окончательный статус выставляется всё равно
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			cm := NewCompetitionManager(out, &cfg.Config{Laps: 2, LapLen: 1000, StartDelta: "00:01:30", Rules: tt.rules})
+			startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+			events := []lh.EventInfo{
+				{EventId: 1, CompetitorId: 1, EventTime: startTime}, //Только зарегистрирован
+				{EventId: 1, CompetitorId: 2, EventTime: startTime},
+				{EventId: 2, CompetitorId: 2, ExtraParams: "12:00:00.000", EventTime: startTime}, //Получил старт, но не стартовал
+				{EventId: 1, CompetitorId: 3, EventTime: startTime},
+				{EventId: 2, CompetitorId: 3, ExtraParams: "12:00:00.000", EventTime: startTime},
+				{EventId: 4, CompetitorId: 3, EventTime: startTime.Add(time.Second)},
+				{EventId: 10, CompetitorId: 3, EventTime: startTime.Add(10 * time.Minute)}, //Прошёл один круг из двух
+			}
+			for _, e := range events {
+				if err := cm.HandleEvent(e); err != nil {
+					t.Fatalf("HandleEvent failed: %v", err)
+				}
+			}
+			cm.Finalize()
+			cm.Finalize() //Повторный вызов ничего не меняет
+
+			for id, want := range map[int]Status{1: StatusDNS, 2: StatusDNS, 3: StatusDNF} {
+				if got := cm.competitors[id].Status; got != want {
+					t.Errorf("Competitor %d status = %v, want %v", id, got, want)
+				}
+			}
+			if got := cm.competitors[3].TotalTime; got != 10*time.Minute {
+				t.Errorf("Competitor 3 total time = %v, want 10m0s", got)
+			}
+
+			diags := cm.Diagnostics()
+			if len(diags) != 3 {
+				t.Fatalf("Expected 3 diagnostics, got %d: %v", len(diags), diags)
+			}
+			for i, want := range []string{"never started, classified as NotStarted", "never started, classified as NotStarted", "did not finish(1 of 2 laps ended), classified as NotFinished"} {
+				if diags[i].Section != SectionFinalization || diags[i].Message != want {
+					t.Errorf("diagnostic %d = %+v, want %q in %s", i, diags[i], want, SectionFinalization)
+				}
+			}
+
+			if err := cm.GenerateReport(); err != nil {
+				t.Fatalf("GenerateReport failed: %v", err)
+			}
+			want := "[NotFinished] 3 [{00:10:00.000, 1.666}, {,}] {,} 0/0 tooFewLaps\n" +
+				"[NotStarted] 1 [{,}, {,}] {,} 0/0 noStart\n" +
+				"[NotStarted] 2 [{,}, {,}] {,} 0/0 noStart\n"
+			if out.String() != want {
+				t.Errorf("GenerateReport() =\n%s\nwant\n%s", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestCloseRace(t *testing.T) {
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	finished := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime},
+		{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)},
+		{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(10 * time.Minute)},
+	}
+	late := lh.EventInfo{EventId: 1, CompetitorId: 2, EventTime: startTime.Add(time.Hour)}
+
+	tests := []struct {
+		name       string
+		config     cfg.Config
+		events     []lh.EventInfo
+		wantClosed string //Время закрытия, пусто - гонка не закрылась до вызова Close
+	}{
+		{"close time", cfg.Config{CloseTime: "12:30:00.000"}, append(append([]lh.EventInfo{}, finished...), late), "12:30:00.000"},
+		{"operator event", cfg.Config{CloseEventId: EventRaceClosed},
+			append(append([]lh.EventInfo{}, finished...), lh.EventInfo{EventId: EventRaceClosed, EventTime: startTime.Add(20 * time.Minute)}, late), "12:20:00.000"},
+		{"all competitors done", cfg.Config{CloseWhenAllDone: true}, append(append([]lh.EventInfo{}, finished...), late), "12:10:00.000"},
+		{"no trigger", cfg.Config{}, finished, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			config := tt.config
+			config.Laps, config.LapLen, config.StartDelta = 1, 1000, "00:01:30"
+			cm := NewCompetitionManager(out, &config)
+
+			var closeErr error
+			for _, e := range tt.events {
+				if err := cm.HandleEvent(e); err != nil {
+					closeErr = err
+					break
+				}
+			}
+			if tt.wantClosed == "" {
+				if closeErr != nil || cm.Closed() {
+					t.Fatalf("Race closed unexpectedly: %v", closeErr)
+				}
+				if err := cm.Close(); err != nil {
+					t.Fatalf("Close failed: %v", err)
+				}
+			} else {
+				if !errors.Is(closeErr, ErrRaceClosed) {
+					t.Fatalf("HandleEvent error = %v, want ErrRaceClosed", closeErr)
+				}
+				want := "race was closed at " + tt.wantClosed + ", no more events are accepted"
+				if closeErr.Error() != want {
+					t.Errorf("HandleEvent error = %q, want %q", closeErr, want)
+				}
+			}
+			if err := cm.Close(); err != nil { //Повторное закрытие ничего не делает и отчёт второй раз не пишет
+				t.Fatalf("Close failed: %v", err)
+			}
+			if err := cm.HandleEvent(late); !errors.Is(err, ErrRaceClosed) {
+				t.Errorf("HandleEvent after close error = %v, want ErrRaceClosed", err)
+			}
+
+			if want := "[00:10:00.000] 1 [{00:10:00.000, 1.666}] {,} 0/0\n"; out.String() != want {
+				t.Errorf("Report = %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestCloseTimeClock(t *testing.T) {
+	config := &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30", CloseTime: "12:30:00.000"}
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	out := new(bytes.Buffer)
+	cm := NewCompetitionManager(out, config)
+	for _, e := range []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime},
+		{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)},
+		{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(10 * time.Minute)},
+	} {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+
+	loop := cm.Run()
+	defer loop.Stop()
+	wallClock := time.Date(2026, 3, 1, 12, 29, 0, 0, time.Local) //Часы идут сами, новых событий нет
+	var mu sync.Mutex
+	now := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return wallClock
+	}
+	errs := loop.StartClock(time.Millisecond, now)
+
+	closed := func() bool {
+		var c bool
+		loop.Do(func(cm *CompetitionManager) { c = cm.Closed() })
+		return c
+	}
+	time.Sleep(10 * time.Millisecond)
+	if closed() {
+		t.Fatal("Race closed before closeTime")
+	}
+	mu.Lock()
+	wallClock = wallClock.Add(2 * time.Minute)
+	mu.Unlock()
+	for deadline := time.Now().Add(5 * time.Second); !closed(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Race was not closed by the clock")
+		}
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatalf("Clock failed: %v", err)
+	default:
+	}
+	err := loop.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 2, EventTime: startTime.Add(31 * time.Minute)})
+	if err == nil || err.Error() != "race was closed at 12:30:00.000, no more events are accepted" {
+		t.Errorf("HandleEvent after close error = %v", err)
+	}
+	var report string
+	loop.Do(func(cm *CompetitionManager) { report = out.String() })
+	if want := "[00:10:00.000] 1 [{00:10:00.000, 1.666}] {,} 0/0\n"; report != want {
+		t.Errorf("Report = %q, want %q", report, want)
+	}
+}
+
+type memoryJournal struct {
+	events []lh.EventInfo
+	err    error
+}
+
+func (j *memoryJournal) Append(eventInfo lh.EventInfo) error {
+	if j.err != nil {
+		return j.err
+	}
+	j.events = append(j.events, eventInfo)
+	return nil
+}
+
+func TestReplayFromJournal(t *testing.T) {
+	config := &cfg.Config{Laps: 2, LapLen: 3500, PenaltyLen: 150, FiringLines: 1, StartDelta: "00:01:30"}
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime},
+		{EventId: 1, CompetitorId: 1, EventTime: startTime}, //Дубликат тоже попадает в журнал
+		{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)},
+		{EventId: 5, CompetitorId: 1, ExtraParams: "1", EventTime: startTime.Add(time.Minute)},
+		{EventId: 6, CompetitorId: 1, ExtraParams: "3", EventTime: startTime.Add(time.Minute + time.Second)},
+		{EventId: 6, CompetitorId: 7, ExtraParams: "3", EventTime: startTime.Add(time.Minute + time.Second)}, //Отклонённое событие
+		{EventId: 7, CompetitorId: 1, EventTime: startTime.Add(2 * time.Minute)},
+		{EventId: 8, CompetitorId: 1, EventTime: startTime.Add(3 * time.Minute)},
+		{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(12 * time.Minute)},
+	}
+
+	original := NewCompetitionManager(new(bytes.Buffer), config)
+	journal := &memoryJournal{}
+	original.SetJournal(journal)
+	for _, e := range events {
+		original.HandleEvent(e)
+	}
+	if len(journal.events) != len(events)-1 { //Отклонённое событие в журнал не попадает
+		t.Fatalf("Expected %d journal records, got %d", len(events)-1, len(journal.events))
+	}
+
+	restored := NewCompetitionManager(new(bytes.Buffer), config)
+	observer := &recordingObserver{}
+	restored.AddObserver(observer)
+	restored.SetJournal(journal)
+	if err := restored.Replay(journal.events); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(restored.competitors, original.competitors) {
+		t.Errorf("Restored competitors = %+v, want %+v", restored.competitors[1], original.competitors[1])
+	}
+	if !reflect.DeepEqual(restored.Diagnostics(), original.Diagnostics()) {
+		t.Errorf("Restored diagnostics = %v, want %v", restored.Diagnostics(), original.Diagnostics())
+	}
+	if len(observer.events) != 0 || len(journal.events) != len(events)-1 {
+		t.Errorf("Replay must not log or journal events again: observed %d, journal %d", len(observer.events), len(journal.events))
+	}
+
+	next := lh.EventInfo{EventId: 9, CompetitorId: 1, EventTime: startTime.Add(4 * time.Minute)} //После восстановления работа продолжается как обычно
+	if err := restored.HandleEvent(next); err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if len(observer.events) != 1 || len(journal.events) != len(events) {
+		t.Errorf("Expected the new event to be logged and journaled: observed %d, journal %d", len(observer.events), len(journal.events))
+	}
+}
+
+func TestRestartAfterRejectedEvent(t *testing.T) {
+	config := &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30"}
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	accepted := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime},
+		{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)},
+		{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(5 * time.Minute)},
+	}
+	rejected := []lh.EventInfo{
+		{EventId: 4, CompetitorId: 77, EventTime: startTime.Add(6 * time.Minute)}, //Незарегистрированный участник
+		{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(7 * time.Minute)}, //Лишний круг
+	}
+
+	for _, bad := range rejected {
+		journal := &memoryJournal{}
+		original := NewCompetitionManager(new(bytes.Buffer), config)
+		original.SetJournal(journal)
+		for _, e := range accepted {
+			if err := original.HandleEvent(e); err != nil {
+				t.Fatalf("HandleEvent failed: %v", err)
+			}
+		}
+		before, _ := original.Competitor(1)
+		if err := original.HandleEvent(bad); err == nil {
+			t.Fatalf("HandleEvent(%+v) must fail", bad)
+		}
+		if after, _ := original.Competitor(1); !reflect.DeepEqual(after, before) {
+			t.Errorf("Rejected event %d changed the competitor: %+v, want %+v", bad.EventId, after, before)
+		}
+		if len(journal.events) != len(accepted) || original.EventCount() != len(accepted) {
+			t.Fatalf("Rejected event must not be journaled or counted: journal %d, count %d", len(journal.events), original.EventCount())
+		}
+
+		restarted := NewCompetitionManager(new(bytes.Buffer), config) //После перезапуска та же строка инпута снова отклоняется, а не проходит молча
+		if err := restarted.Replay(journal.events); err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+		if restarted.EventCount() != len(accepted) {
+			t.Errorf("EventCount() = %d, want %d", restarted.EventCount(), len(accepted))
+		}
+		if err := restarted.HandleEvent(bad); err == nil {
+			t.Errorf("HandleEvent(%+v) after restart must still fail", bad)
+		}
+	}
+
+	broken := NewCompetitionManager(new(bytes.Buffer), config)
+	if err := broken.Replay(rejected[:1]); err == nil || !strings.Contains(err.Error(), "journal record 1") {
+		t.Errorf("Replay() of a record the manager rejects error = %v", err)
+	}
+}
+
+func TestJournalFailureLeavesStateUnchanged(t *testing.T) {
+	cm := NewCompetitionManager(new(bytes.Buffer), &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30"})
+	observer := &recordingObserver{}
+	cm.AddObserver(observer)
+	journal := &memoryJournal{}
+	cm.SetJournal(journal)
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, e := range []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime},
+		{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime},
+	} {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+
+	journal.err = errors.New("disk full") //Событие не записалось в журнал - значит, менеджер не должен его применять
+	start := lh.EventInfo{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)}
+	if err := cm.HandleEvent(start); err == nil {
+		t.Fatal("HandleEvent() expected the journal error")
+	}
+	if c := cm.competitors[1]; c.Started || c.Status == StatusStarted {
+		t.Errorf("Event that was not journaled changed the competitor: started %v, status %v", c.Started, c.Status)
+	}
+	if cm.EventCount() != 2 || len(observer.events) != 2 {
+		t.Errorf("Event that was not journaled was counted or logged: count %d, observed %d", cm.EventCount(), len(observer.events))
+	}
+
+	journal.err = nil
+	if err := cm.HandleEvent(start); err != nil { //Повтор после ошибки журнала не считается дубликатом
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if c := cm.competitors[1]; !c.Started || len(journal.events) != 3 {
+		t.Errorf("Expected the retried event to be applied and journaled: started %v, journal %d", c.Started, len(journal.events))
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	config := &cfg.Config{Laps: 2, LapLen: 3500, PenaltyLen: 150, FiringLines: 1, StartDelta: "00:01:30", Rules: map[string]string{"skippedPenalty": "DSQ"}}
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime},
+		{EventId: 1, CompetitorId: 2, EventTime: startTime},
+		{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime},
+		{EventId: 2, CompetitorId: 2, ExtraParams: "12:00:30.000", EventTime: startTime},
+		{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)},
+		{EventId: 4, CompetitorId: 2, EventTime: startTime.Add(31 * time.Second)},
+		{EventId: 5, CompetitorId: 1, ExtraParams: "1", EventTime: startTime.Add(5 * time.Minute)},
+		{EventId: 6, CompetitorId: 1, ExtraParams: "2", EventTime: startTime.Add(5*time.Minute + time.Second)},
+		{EventId: 7, CompetitorId: 1, EventTime: startTime.Add(6 * time.Minute)},
+		{EventId: 8, CompetitorId: 1, EventTime: startTime.Add(7 * time.Minute)}, //Снимок делается посреди штрафных кругов
+		{EventId: 9, CompetitorId: 1, EventTime: startTime.Add(9 * time.Minute)},
+		{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(13 * time.Minute)},
+		{EventId: 10, CompetitorId: 2, EventTime: startTime.Add(14 * time.Minute)}, //Пропустил рубеж и штрафные
+		{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(26 * time.Minute)},
+	}
+	const cut = 10
+
+	whole := new(bytes.Buffer)
+	cm := NewCompetitionManager(whole, config)
+	for _, e := range events {
+		cm.HandleEvent(e)
+	}
+	cm.Close()
+
+	first := NewCompetitionManager(new(bytes.Buffer), config)
+	for _, e := range events[:cut] {
+		first.HandleEvent(e)
+	}
+	data := new(bytes.Buffer)
+	if err := first.WriteSnapshot(data); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	snapshot, err := ReadSnapshot(bytes.NewReader(data.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadSnapshot failed: %v", err)
+	}
+	resumed := new(bytes.Buffer)
+	restored := snapshot.Restore(resumed)
+	if !reflect.DeepEqual(restored.competitors, first.competitors) {
+		t.Errorf("Restored competitor = %+v, want %+v", restored.competitors[1], first.competitors[1])
+	}
+	if restored.EventCount() != cut {
+		t.Errorf("EventCount() = %d, want %d", restored.EventCount(), cut)
+	}
+	again := new(bytes.Buffer) //Одно и то же состояние даёт один и тот же снимок
+	restored.WriteSnapshot(again)
+	if again.String() != data.String() {
+		t.Errorf("Snapshot of the restored manager differs:\n%s\nwant\n%s", again, data)
+	}
+
+	for _, e := range events[cut:] {
+		restored.HandleEvent(e)
+	}
+	restored.Close()
+	if resumed.String() != whole.String() {
+		t.Errorf("Report after restore =\n%s\nwant\n%s", resumed, whole)
+	}
+	if !reflect.DeepEqual(restored.Diagnostics(), cm.Diagnostics()) {
+		t.Errorf("Diagnostics after restore = %v, want %v", restored.Diagnostics(), cm.Diagnostics())
+	}
+}
+
+func TestReadSnapshotErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"version": 99, "config": {}}`, "unsupported snapshot version(99)"},
+		{`{"version": 1}`, "snapshot has no config"},
+		{`{"version": 1, "config": {"rules": {"cutoff": "maybe"}}}`, "invalid status(maybe)"},
+		{`not json`, "unable to read snapshot"},
+	}
+	for _, tt := range tests {
+		if _, err := ReadSnapshot(strings.NewReader(tt.data)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ReadSnapshot(%s) error = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+	ru, _ := i18n.New("ru")
+	_, err := ReadSnapshot(strings.NewReader(`{"version": 99, "config": {}}`))
+	if got, want := ru.Localize(err), "неподдерживаемая версия снимка(99), ожидается 1"; got != want {
+		t.Errorf("Localize(ReadSnapshot()) = %q, want %q", got, want)
+	}
+}
+
+func TestCorrectionsInReport(t *testing.T) {
+	out := new(bytes.Buffer)
+	cm := NewCompetitionManager(out, &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30"})
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	voided := lh.EventInfo{EventId: 1, CompetitorId: 2, EventTime: startTime}
+	inserted := lh.EventInfo{EventId: 1, CompetitorId: 1, EventTime: startTime}
+	cm.SetCorrections([]corrections.AuditEntry{
+		{Correction: corrections.Correction{Op: corrections.OpVoid, Seq: 1, By: "jury", Reason: "double entry"}, Original: &voided, Affected: []int{2}},
+		{Correction: corrections.Correction{Op: corrections.OpInsert, Seq: 1, By: "secretary"}, New: &inserted, Affected: []int{1}},
+	})
+	if err := cm.HandleEvent(inserted); err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	cm.Finalize()
+	if err := cm.GenerateReport(); err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+	want := "[NotStarted] 1 [{,}] {,} 0/0 noStart\n" +
+		"Corrections:\n" +
+		"1. event #1 <[12:00:00.000] 1 2> voided by jury(double entry), affected competitors: 2\n" +
+		"2. event <[12:00:00.000] 1 1> inserted after #1 by secretary, affected competitors: 1\n"
+	if out.String() != want {
+		t.Errorf("GenerateReport() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestParseAdjustments(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Adjustment
+		wantErr string
+	}{
+		{"penalty and credit", `{"competitor":1,"delta":"+00:02:00","reason":"rule violation"}
+
+{"competitor":2,"delta":"-00:00:30","reason":"obstructed"}`, []Adjustment{
+			{CompetitorId: 1, Delta: 2 * time.Minute, Reason: "rule violation"},
+			{CompetitorId: 2, Delta: -30 * time.Second, Reason: "obstructed"},
+		}, ""},
+		{"no sign", `{"competitor":1,"delta":"00:02:00","reason":"x"}`, nil, "must start with + or -"},
+		{"bad delta", `{"competitor":1,"delta":"+2m","reason":"x"}`, nil, "unable to parse"},
+		{"no reason", `{"competitor":1,"delta":"+00:02:00"}`, nil, "missing reason"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAdjustments(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseAdjustments() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAdjustments() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAdjustments() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	ru, _ := i18n.New("ru")
+	_, err := ParseAdjustments(strings.NewReader(`{"competitor":1,"delta":"00:02:00","reason":"x"}`))
+	if got, want := ru.Localize(err), "некорректное решение жюри в строке 1: поправка(00:02:00) должна начинаться с + или -"; got != want {
+		t.Errorf("Localize(ParseAdjustments()) = %q, want %q", got, want)
+	}
+}
+
+func TestAdjustmentsInReport(t *testing.T) {
+	out := new(bytes.Buffer)
+	cm := NewCompetitionManager(out, &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30"})
+	cm.SetAdjustments([]Adjustment{
+		{CompetitorId: 1, Delta: 2 * time.Minute, Reason: "rule violation"},
+		{CompetitorId: 2, Delta: -30 * time.Second, Reason: "obstructed"},
+		{CompetitorId: 3, Delta: time.Minute, Reason: "false start"},
+	})
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime},
+		{EventId: 1, CompetitorId: 2, EventTime: startTime},
+		{EventId: 1, CompetitorId: 3, EventTime: startTime},
+		{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime.Add(time.Millisecond)},
+		{EventId: 2, CompetitorId: 2, ExtraParams: "12:00:00.000", EventTime: startTime.Add(2 * time.Millisecond)},
+		{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)},
+		{EventId: 4, CompetitorId: 2, EventTime: startTime.Add(2 * time.Second)},
+		{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(4 * time.Minute)}, //Финишировал первым, но штраф отодвигает его назад
+		{EventId: 10, CompetitorId: 2, EventTime: startTime.Add(5 * time.Minute)},
+	}
+	for _, e := range events {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+	cm.Finalize()
+	if err := cm.GenerateReport(); err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+	want := "[00:04:30.000] 2 [{00:05:00.000, 3.333}] {,} 0/0 -00:00:30.000\n" +
+		"[00:06:00.000] 1 [{00:04:00.000, 4.166}] {,} 0/0 +00:02:00.000\n" +
+		"[NotStarted] 3 [{,}] {,} 0/0 - noStart\n" +
+		"Adjustments:\n" +
+		"1. competitor(1) +00:02:00.000: rule violation\n" +
+		"2. competitor(2) -00:00:30.000: obstructed\n" +
+		"3. competitor(3) +00:01:00.000: false start\n"
+	if out.String() != want {
+		t.Errorf("GenerateReport() =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	diags := cm.Diagnostics()
+	last := diags[len(diags)-1]
+	if last.Section != SectionAdjustments || last.CompetitorId != 3 || !strings.Contains(last.Message, "is ignored, competitor is NotStarted") {
+		t.Errorf("Last diagnostic = %+v, want ignored adjustment for competitor 3", last)
+	}
+}
+
+func TestEventLoopConcurrentAccess(t *testing.T) {
+	const competitors = 20
+	out := new(bytes.Buffer)
+	loop := NewCompetitionManager(out, &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30"}).Run()
+	defer loop.Stop()
+
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	errs := make(chan error, competitors)
+	readersDone := make(chan struct{})
+	for id := 1; id <= competitors; id++ { //Каждый участник - своя горутина, события одного участника идут по порядку
+		go func(id int) {
+			offset := time.Duration(id) * time.Millisecond
+			for _, e := range []lh.EventInfo{
+				{EventId: 1, CompetitorId: id, EventTime: startTime.Add(offset)},
+				{EventId: 2, CompetitorId: id, ExtraParams: "12:00:00.000", EventTime: startTime.Add(time.Second + offset)},
+				{EventId: 4, CompetitorId: id, EventTime: startTime.Add(2*time.Second + offset)},
+				{EventId: 10, CompetitorId: id, EventTime: startTime.Add(time.Duration(id)*time.Minute + offset)},
+			} {
+				if err := loop.HandleEvent(e); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}(id)
+	}
+	go func() { //Параллельно читаем протокол и участников, пока идёт приём событий
+		defer close(readersDone)
+		for i := 0; i < 200; i++ {
+			standings, err := loop.Standings()
+			if err != nil {
+				t.Errorf("Standings() error = %v", err)
+				return
+			}
+			for _, s := range standings {
+				_ = s.LapTimes
+			}
+			if _, _, err := loop.Competitor(i%competitors + 1); err != nil {
+				t.Errorf("Competitor() error = %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < competitors; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+	<-readersDone
+
+	standings, err := loop.Standings()
+	if err != nil {
+		t.Fatalf("Standings() error = %v", err)
+	}
+	if len(standings) != competitors {
+		t.Fatalf("Expected %d standings, got %d", competitors, len(standings))
+	}
+	for i, s := range standings {
+		if s.Place != i+1 || s.CompetitorId != i+1 || s.Status != StatusFinished {
+			t.Errorf("Standing %d = place %d competitor %d %v, want place %d competitor %d Finished", i, s.Place, s.CompetitorId, s.Status, i+1, i+1)
+		}
+	}
+
+	c, ok, err := loop.Competitor(3)
+	if err != nil || !ok {
+		t.Fatalf("Competitor(3) = %v, %v", ok, err)
+	}
+	c.LapTimes[0] = 0 //Копия не связана с состоянием менеджера
+	if again, _, _ := loop.Competitor(3); again.LapTimes[0] == 0 {
+		t.Error("Competitor() must return a copy")
+	}
+	if _, ok, _ := loop.Competitor(99); ok {
+		t.Error("Competitor(99) must not exist")
+	}
+
+	if err := loop.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if err := loop.HandleEvent(lh.EventInfo{EventId: 1, CompetitorId: 99, EventTime: startTime.Add(time.Hour)}); !errors.Is(err, ErrRaceClosed) {
+		t.Errorf("HandleEvent() after Close error = %v, want ErrRaceClosed", err)
+	}
+	if strings.Count(out.String(), "\n") != competitors {
+		t.Errorf("Expected a report with %d lines, got:\n%s", competitors, out.String())
+	}
+
+	loop.Stop()
+	loop.Stop() //Повторная остановка ничего не делает
+	if _, err := loop.Standings(); !errors.Is(err, ErrLoopStopped) {
+		t.Errorf("Standings() after Stop error = %v, want ErrLoopStopped", err)
+	}
+}
+
+func TestRegisterCloseEvent(t *testing.T) {
+	if err := RegisterCloseEvent(4); err == nil { //Занято событием "стартовал"
+		t.Error("RegisterCloseEvent(4) must fail")
+	}
+	for _, id := range []int{0, EventRaceClosed, 20, 20} { //Повторная регистрация, например для второй гонки, не ошибка
+		if err := RegisterCloseEvent(id); err != nil {
+			t.Fatalf("RegisterCloseEvent(%d) error = %v", id, err)
+		}
+	}
+
+	eventInfo, err := lh.NewParser(nil).ParseLine("[12:30:00.000] 20 0")
+	if err != nil {
+		t.Fatalf("ParseLine() of the configured close event error = %v", err)
+	}
+	cm := NewCompetitionManager(new(bytes.Buffer), &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30", CloseEventId: 20})
+	if err := cm.HandleEvent(eventInfo); err != nil {
+		t.Fatalf("HandleEvent failed: %v", err)
+	}
+	if !cm.Closed() {
+		t.Error("Event 20 must close the race when closeEventId is 20")
+	}
+}
+
+func TestRejectedEventCanBeSentAgain(t *testing.T) {
+	cm := NewCompetitionManager(new(bytes.Buffer), &cfg.Config{Laps: 1, LapLen: 1000, StartDelta: "00:01:30"})
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	start := lh.EventInfo{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)}
+	if err := cm.HandleEvent(start); err == nil { //Участник ещё не зарегистрирован
+		t.Fatal("HandleEvent() expected error for an unregistered competitor")
+	}
+	if !cm.lastEventTime.IsZero() {
+		t.Errorf("Rejected event moved lastEventTime to %v", cm.lastEventTime)
+	}
+
+	for _, e := range []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: startTime},
+		{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime},
+		start, //То же событие ещё раз - теперь оно должно примениться, а не уйти в дубликаты
+	} {
+		if err := cm.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent failed: %v", err)
+		}
+	}
+	if c := cm.competitors[1]; !c.Started || c.Status != StatusStarted {
+		t.Errorf("Competitor = started %v, status %v, want started", c.Started, c.Status)
+	}
+	if diags := cm.Diagnostics(); len(diags) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diags)
+	}
+}
@@ -0,0 +1,53 @@
+package competitionmgr
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"yadro_test/internal/corrections"
+	lh "yadro_test/internal/logger"
+)
+
+func (cm *CompetitionManager) SetCorrections(audit []corrections.AuditEntry) { //Исправления уже применены к потоку событий, менеджеру они нужны только для протокола в отчёте
+	cm.corrections = audit
+}
+
+func (cm *CompetitionManager) writeCorrections() error {
+	if len(cm.corrections) == 0 {
+		return nil
+	}
+	lines := []string{cm.loc.T("report.corrections")}
+	for i, c := range cm.corrections {
+		by := c.By
+		if c.Reason != "" {
+			by += "(" + c.Reason + ")"
+		}
+		ids := make([]string, 0, len(c.Affected))
+		for _, id := range c.Affected {
+			ids = append(ids, strconv.Itoa(id))
+		}
+		affected := strings.Join(ids, ", ")
+
+		switch c.Op {
+		case corrections.OpVoid:
+			lines = append(lines, cm.loc.T("report.void", i+1, c.Seq, formatEvent(c.Original), by, affected))
+		case corrections.OpReplace:
+			lines = append(lines, cm.loc.T("report.replace", i+1, c.Seq, formatEvent(c.Original), formatEvent(c.New), by, affected))
+		case corrections.OpInsert:
+			lines = append(lines, cm.loc.T("report.insert", i+1, c.Seq, formatEvent(c.New), by, affected))
+		}
+	}
+	if _, err := io.WriteString(cm.output, strings.Join(lines, "\n")+"\n"); err != nil {
+		return cm.loc.Errorf("err.writeReport", err)
+	}
+	return nil
+}
+
+func formatEvent(e *lh.EventInfo) string {
+	if e == nil {
+		return "-"
+	}
+	return fmt.Sprintf("<%s>", lh.TextFormat{}.Format(*e))
+}
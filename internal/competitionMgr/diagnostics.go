@@ -0,0 +1,93 @@
+package competitionmgr
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+type Severity int
+
+const (
+	Warning Severity = iota //Событие проигнорировано, но на результат это не влияет
+	Error                   //Данные противоречат друг другу, результат может быть неверным
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "ERROR"
+	}
+	return "WARNING"
+}
+
+func (s Severity) messageKey() string {
+	if s == Error {
+		return "diagnostics.error"
+	}
+	return "diagnostics.warning"
+}
+
+const ( //Разделы, по которым группируются аномалии в выводе
+	SectionDuplicates = "duplicates"
+	SectionConflicts  = "conflicts"
+)
+
+type Diagnostic struct {
+	Severity     Severity
+	Section      string
+	EventTime    time.Time
+	CompetitorId int
+	Message      string
+}
+
+func (cm *CompetitionManager) addDiagnostic(severity Severity, section string, eventTime time.Time, competitorId int, key string, args ...any) {
+	cm.diagnostics = append(cm.diagnostics, Diagnostic{
+		Severity:     severity,
+		Section:      section,
+		EventTime:    eventTime,
+		CompetitorId: competitorId,
+		Message:      cm.loc.T(key, args...),
+	})
+}
+
+func (cm *CompetitionManager) Diagnostics() []Diagnostic {
+	return cm.diagnostics
+}
+
+func (cm *CompetitionManager) WriteDiagnostics(w io.Writer) error {
+	warnings, errors := 0, 0
+	sections := make([]string, 0)
+	bySection := make(map[string][]Diagnostic)
+	for _, d := range cm.diagnostics { //Группируем по разделам, сохраняя порядок их первого появления
+		if d.Severity == Error {
+			errors += 1
+		} else {
+			warnings += 1
+		}
+		if _, ok := bySection[d.Section]; !ok {
+			sections = append(sections, d.Section)
+		}
+		bySection[d.Section] = append(bySection[d.Section], d)
+	}
+
+	if _, err := fmt.Fprintln(w, cm.loc.T("diagnostics.header", warnings, errors)); err != nil {
+		return cm.loc.Errorf("err.writeDiagnostics", err)
+	}
+	for _, section := range sections {
+		if _, err := fmt.Fprintf(w, "[%s]\n", cm.loc.T("section."+section)); err != nil {
+			return cm.loc.Errorf("err.writeDiagnostics", err)
+		}
+		for _, d := range bySection[section] {
+			_, err := fmt.Fprintf(w, "%s [%s] %s: %s\n",
+				cm.loc.T(d.Severity.messageKey()),
+				d.EventTime.Format("15:04:05.000"),
+				cm.loc.T("diagnostics.competitor", d.CompetitorId),
+				d.Message,
+			)
+			if err != nil {
+				return cm.loc.Errorf("err.writeDiagnostics", err)
+			}
+		}
+	}
+	return nil
+}
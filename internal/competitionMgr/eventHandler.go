@@ -1,22 +1,47 @@
 package competitionmgr
 
 import (
-	"fmt"
-	"os"
-	"strconv"
+	"errors"
+	"io"
 	"time"
 
 	timeParser "yadro_test/common"
 	"yadro_test/internal/cfg"
+	"yadro_test/internal/corrections"
+	"yadro_test/internal/i18n"
 	lh "yadro_test/internal/logger"
 )
 
-const TargetsPerFiringLine = 5
+const TargetsPerFiringLine = lh.TargetsPerFiringLine
 
 type CompetitionManager struct {
-	outputFile  *os.File
-	cfg         *cfg.Config
-	competitors map[int]*Competitor
+	output        io.Writer
+	cfg           *cfg.Config
+	competitors   map[int]*Competitor
+	seenEvents    map[eventKey]struct{} //Уже обработанные события, чтобы ловить точные дубликаты
+	diagnostics   []Diagnostic
+	loc           i18n.Localizer //Язык статусов, диагностики и ошибок
+	observers     []EventObserver
+	pending       []lh.EventInfo  //Исходящие события, сгенерированные при обработке текущего
+	rules         map[Rule]string //Правило -> код статуса(DNS, DNF, DSQ или off)
+	lastEventTime time.Time
+	leaderLaps    uint //Сколько кругов прошёл лидер
+	cutoffReached bool
+	finalized     bool
+	closed        bool
+	closedAt      time.Time
+	journal       EventJournal
+	replaying     bool //Идёт восстановление из журнала: не пишем журнал, лог и отчёт повторно
+	corrections   []corrections.AuditEntry
+	adjustments   []Adjustment //Штрафы и зачёты времени от жюри
+	eventCount    int          //Сколько событий входного потока принято, совпадает с числом записей журнала
+}
+
+type eventKey struct {
+	eventId      int
+	competitorId int
+	eventTime    time.Time
+	extraParams  string
 }
 
 type Competitor struct {
@@ -25,96 +50,267 @@ type Competitor struct {
 	LastLapTime      time.Time
 	LapsEnded        uint
 	PenaltyLapsEnter time.Time
+	PenaltyLoops     []int //Число пар 8/9 после каждого огневого рубежа
+	PenaltyChecked   int   //Сколько рубежей уже сверено со штрафными кругами
+	PenaltySkipped   int   //Сколько штрафных кругов участник не пробежал
+	Started          bool
 }
 
-func NewCompetitionManager(outFile *os.File, cfg *cfg.Config) *CompetitionManager {
+func NewCompetitionManager(output io.Writer, cfg *cfg.Config) *CompetitionManager {
+	loc, _ := i18n.New(cfg.Lang) //Неизвестный язык отсекается при запуске, здесь в худшем случае остаёмся на английском
 	return &CompetitionManager{
-		outputFile:  outFile,
+		loc:         loc,
+		output:      output,
 		cfg:         cfg,
 		competitors: make(map[int]*Competitor, 0), //В качестве key будет выступать competitorId. Можно было бы обойтись слайсом, но нет уверенности,
 		// что наши id идут по порядку(и не будет разрывов в номере участников)
+		seenEvents: make(map[eventKey]struct{}),
+		rules:      buildRules(cfg.Rules),
 	}
 }
 
-func (cm CompetitionManager) HandleEvent(eventInfo lh.EventInfo) error {
-	switch eventInfo.EventId { //Если участник зарегался - создаём для него структуру и закидываем её в мапу
-	case 1:
-		cm.competitors[eventInfo.CompetitorId] = &Competitor{
-			ReportInfo: ReportInfo{
-				CompetitorId: eventInfo.CompetitorId,
-				LapTimes:     make([]time.Duration, 0, cm.cfg.Laps),
-				LapSpeeds:    make([]float64, 0, cm.cfg.Laps),
-				Hits:         make([]bool, TargetsPerFiringLine*cm.cfg.FiringLines),
-			},
+func (cm *CompetitionManager) HandleEvent(eventInfo lh.EventInfo) error {
+	if cm.closed {
+		return cm.raceClosedErr()
+	}
+	if closed, err := cm.checkCloseTime(eventInfo.EventTime); err != nil {
+		return err
+	} else if closed { //Само событие не принимается, но гонку закрыло - в журнал оно попадает, чтобы после перезапуска гонка закрылась так же
+		if err := cm.accept(eventInfo); err != nil {
+			return err
 		}
-	case 2: //Если участник получил время, то считаем его как стартовое, т.к. в тз сказано "Total time includes the difference between scheduled and actual start time"
-		competitor := cm.competitors[eventInfo.CompetitorId]
-		startTime, err := timeParser.ConvertStringToTime(eventInfo.ExtraParams)
+		return cm.raceClosedErr()
+	}
+
+	if eventInfo.Payload == nil { //Событие собрано не парсером(например, в тестах) - разбираем доп. параметры сами
+		payload, err := lh.ParsePayload(eventInfo.EventId, eventInfo.ExtraParams)
 		if err != nil {
 			return err
 		}
+		eventInfo.Payload = payload
+	}
 
-		competitor.LastLapTime = startTime
-		competitor.StartTime = startTime
-	case 3, 5: //В целом ничего не требуется в этих случаях
+	key := eventKey{eventInfo.EventId, eventInfo.CompetitorId, eventInfo.EventTime, eventInfo.ExtraParams}
+	if _, ok := cm.seenEvents[key]; ok { //Точный дубликат(например, одно событие пришло с двух устройств) - пропускаем с предупреждением
+		if err := cm.accept(eventInfo); err != nil {
+			return err
+		}
+		cm.addDiagnostic(Warning, SectionDuplicates, eventInfo.EventTime, eventInfo.CompetitorId, "diag.duplicateEvent", eventInfo.EventId)
 		return nil
-	case 4: //Если участние стартанул - надо посчитать, не опоздал ли он на старт, если опоздал - NotStarted статус, пусть подумает о поведении
-		competitor := cm.competitors[eventInfo.CompetitorId]
-		startDeltaDur, err := timeParser.ConvertStringToDuration(cm.cfg.StartDelta)
-		if err != nil {
+	}
+
+	if eventInfo.EventId == cm.cfg.CloseEventId { //Закрытие гонки не относится ни к какому участнику
+		if err := cm.accept(eventInfo); err != nil {
+			return err
+		}
+		cm.remember(key, eventInfo.EventTime)
+		return cm.handleRaceClosed(eventInfo)
+	}
+	if eventInfo.EventId != 1 { //Все события, кроме регистрации, относятся к уже зарегистрированному участнику
+		if _, ok := cm.competitors[eventInfo.CompetitorId]; !ok {
+			return cm.loc.Errorf("err.notRegistered", eventInfo.CompetitorId)
+		}
+	}
+
+	handler, ok := eventHandlers[eventInfo.EventId] //Обработчик берём из реестра, так что новые типы событий добавляются без правки этого метода
+	if !ok {
+		return cm.loc.Errorf("err.unknownEvent", eventInfo.EventId)
+	}
+	if validate, ok := eventValidators[eventInfo.EventId]; ok { //Сначала проверка, потом журнал, потом применение: отклонённое событие не оставляет следов ни в журнале, ни в состоянии
+		if err := validate(cm, cm.competitors[eventInfo.CompetitorId], eventInfo); err != nil {
+			return err
+		}
+	}
+	cutoff, cutoffDue, err := cm.cutoffDue(eventInfo.EventTime)
+	if err != nil {
+		return err
+	}
+	if err := cm.accept(eventInfo); err != nil {
+		return err
+	}
+	cm.remember(key, eventInfo.EventTime)
+
+	if cutoffDue { //Классификация по cutoffTime - часть применения события, её исходящие 32 уходят вместе с ним
+		cm.sweepCutoff(cutoff)
+	}
+	err = handler(cm, cm.competitors[eventInfo.CompetitorId], eventInfo)
+	if errors.Is(err, errEventIgnored) {
+		cm.flush() //Само событие в лог не пишется, но исходящие события по cutoffTime терять нельзя
+		return nil
+	}
+	if err != nil { //Обработчик не должен отклонять событие, для этого есть проверка(EventDef.Validate)
+		cm.pending = cm.pending[:0]
+		return err
+	}
+	cm.notify(eventInfo)                             //В лог попадают только успешно применённые события
+	if cm.cfg.CloseWhenAllDone && cm.allTerminal() { //У всех окончательный статус - гонку можно закрывать
+		return cm.closeAt(eventInfo.EventTime)
+	}
+	return nil
+}
+
+func (cm *CompetitionManager) accept(eventInfo lh.EventInfo) error { //В журнал и в счётчик попадают только проверенные события, до того как менеджер их применит
+	if cm.journal != nil && !cm.replaying {
+		if err := cm.journal.Append(eventInfo); err != nil {
+			return err
+		}
+	}
+	cm.eventCount += 1
+	return nil
+}
+
+func (cm *CompetitionManager) remember(key eventKey, eventTime time.Time) { //Только принятое событие считается увиденным: отклонённое можно прислать ещё раз, и оно применится
+	cm.seenEvents[key] = struct{}{}
+	if cm.lastEventTime.IsZero() || eventTime.After(cm.lastEventTime) { //Время без даты приходится на нулевой год, раньше нулевого time.Time
+		cm.lastEventTime = eventTime
+	}
+}
+
+func (cm *CompetitionManager) handleRegistration(competitor *Competitor, eventInfo lh.EventInfo) error { //Если участник зарегался - создаём для него структуру и закидываем её в мапу
+	if competitor != nil { //Повторная регистрация ничего не меняет, старые данные не затираем
+		cm.addDiagnostic(Warning, SectionDuplicates, eventInfo.EventTime, eventInfo.CompetitorId, "diag.repeatedRegistration")
+		return errEventIgnored
+	}
+	cm.competitors[eventInfo.CompetitorId] = &Competitor{
+		ReportInfo: ReportInfo{
+			CompetitorId: eventInfo.CompetitorId,
+			LapTimes:     make([]time.Duration, 0, cm.cfg.Laps),
+			LapSpeeds:    make([]float64, 0, cm.cfg.Laps),
+			Hits:         make([]bool, TargetsPerFiringLine*cm.cfg.FiringLines),
+		},
+		PenaltyLoops: make([]int, cm.cfg.FiringLines),
+	}
+	return nil
+}
+
+func (cm *CompetitionManager) handleDraw(competitor *Competitor, eventInfo lh.EventInfo) error { //Если участник получил время, то считаем его как стартовое, т.к. в тз сказано "Total time includes the difference between scheduled and actual start time"
+	startTime := eventInfo.Payload.(lh.DrawEvent).StartAt //Тип параметров проверен в validateDraw
+	if !competitor.StartTime.IsZero() {                   //Жеребьёвка уже была: то же время - просто повтор, другое - конфликт, оставляем первое
+		if competitor.StartTime.Equal(startTime) {
+			cm.addDiagnostic(Warning, SectionDuplicates, eventInfo.EventTime, eventInfo.CompetitorId, "diag.repeatedDraw", eventInfo.ExtraParams)
+		} else {
+			cm.addDiagnostic(Error, SectionConflicts, eventInfo.EventTime, eventInfo.CompetitorId, "diag.conflictingDraw",
+				eventInfo.ExtraParams, competitor.StartTime.Format("15:04:05.000"))
+		}
+		return errEventIgnored
+	}
+
+	competitor.LastLapTime = startTime
+	competitor.StartTime = startTime
+	competitor.advance(StatusDrawn)
+	return nil
+}
+
+func (cm *CompetitionManager) validateDraw(competitor *Competitor, eventInfo lh.EventInfo) error {
+	if _, ok := eventInfo.Payload.(lh.DrawEvent); !ok {
+		return cm.loc.Errorf("err.unexpectedPayload", eventInfo.Payload, eventInfo.EventId)
+	}
+	return nil
+}
+
+func (cm *CompetitionManager) validateStart(competitor *Competitor, eventInfo lh.EventInfo) error {
+	_, err := timeParser.ConvertStringToDuration(cm.cfg.StartDelta)
+	return err
+}
+
+func (cm *CompetitionManager) validateHit(competitor *Competitor, eventInfo lh.EventInfo) error {
+	hit, ok := eventInfo.Payload.(lh.HitEvent)
+	if !ok {
+		return cm.loc.Errorf("err.unexpectedPayload", eventInfo.Payload, eventInfo.EventId)
+	}
+	targetNum := TargetsPerFiringLine*competitor.FiringRangeNum + hit.Target - 1
+	maxFiringLines := TargetsPerFiringLine * cm.cfg.FiringLines
+	if targetNum >= maxFiringLines {
+		return cm.loc.Errorf("err.targetOutOfRange", maxFiringLines, targetNum+1)
+	}
+	return nil
+}
+
+func (cm *CompetitionManager) validateLapEnd(competitor *Competitor, eventInfo lh.EventInfo) error {
+	if competitor.LapsEnded == uint(cm.cfg.Laps) {
+		return cm.loc.Errorf("err.tooManyLaps")
+	}
+	if cm.cfg.MaxRaceTime != "" {
+		if _, err := timeParser.ConvertStringToDuration(cm.cfg.MaxRaceTime); err != nil {
 			return err
 		}
-		diff := eventInfo.EventTime.Sub(competitor.LastLapTime)
+	}
+	return nil
+}
+
+func (cm *CompetitionManager) handleNothing(*Competitor, lh.EventInfo) error { //В целом ничего не требуется в этих случаях
+	return nil
+}
+
+func (cm *CompetitionManager) handleStart(competitor *Competitor, eventInfo lh.EventInfo) error { //Если участние стартанул - надо посчитать, не опоздал ли он на старт, если опоздал - срабатывает правило startWindow(по умолчанию NotStarted), пусть подумает о поведении
+	startDeltaDur, _ := timeParser.ConvertStringToDuration(cm.cfg.StartDelta) //Проверено в validateStart
+	competitor.Started = true
+	competitor.advance(StatusStarted)
+	diff := eventInfo.EventTime.Sub(competitor.LastLapTime)
 
-		if diff > startDeltaDur || diff < 0 {
-			competitor.Status = "NotStarted"
+	if diff > startDeltaDur || diff < 0 {
+		if cm.applyRule(competitor, RuleStartWindow, eventInfo.EventTime) {
 			competitor.TotalTime = startDeltaDur //Вот тут не уверен, что нужно было именно такое время, может быть между запланированным и актуальным временем, но а если
 			// он в целом не пришёл на старт?
 		}
-	case 6: //Просто обрабатываем, в какую мишень попал и сохраняем в мапу Hits, чтобы потом считать промахи/попадания
-		competitor := cm.competitors[eventInfo.CompetitorId]
-		targetNum, err := strconv.Atoi(eventInfo.ExtraParams)
-		if err != nil {
-			return fmt.Errorf("unable to convert targetNum to int(%s)", eventInfo.ExtraParams)
-		}
-		targetNum = TargetsPerFiringLine*competitor.FiringRangeNum + targetNum - 1
-		maxFiringLines := TargetsPerFiringLine * cm.cfg.FiringLines
-		if targetNum > maxFiringLines {
-			return fmt.Errorf("target num can`t be more than %d(got %d)", maxFiringLines, targetNum)
+	}
+	return nil
+}
+
+func (cm *CompetitionManager) handleHit(competitor *Competitor, eventInfo lh.EventInfo) error { //Просто обрабатываем, в какую мишень попал и сохраняем в мапу Hits, чтобы потом считать промахи/попадания
+	hit := eventInfo.Payload.(lh.HitEvent) //Тип параметров и номер мишени проверены в validateHit
+	competitor.Hits[TargetsPerFiringLine*competitor.FiringRangeNum+hit.Target-1] = true
+	return nil
+}
+
+func (cm *CompetitionManager) handleFiringRangeLeft(competitor *Competitor, eventInfo lh.EventInfo) error { //Закончил стрельбище - сохраним
+	competitor.FiringRangeNum += 1
+	return nil
+}
+
+func (cm *CompetitionManager) handlePenaltyEnter(competitor *Competitor, eventInfo lh.EventInfo) error { //Забежал на штрафные - запомним
+	competitor.PenaltyLapsEnter = eventInfo.EventTime
+	return nil
+}
+
+func (cm *CompetitionManager) handlePenaltyLeft(competitor *Competitor, eventInfo lh.EventInfo) error { //Выбежал со штрафных - посчитаем время, чтобы потом в final report отправить
+	dur := eventInfo.EventTime.Sub(competitor.PenaltyLapsEnter)
+	competitor.PenaltyTime += dur
+	if rangeIdx := competitor.FiringRangeNum - 1; rangeIdx >= 0 && rangeIdx < len(competitor.PenaltyLoops) {
+		competitor.PenaltyLoops[rangeIdx] += 1
+	}
+	cm.checkPenaltySpeed(competitor, eventInfo.EventTime, dur)
+	return nil
+}
 
+func (cm *CompetitionManager) handleLapEnd(competitor *Competitor, eventInfo lh.EventInfo) error { //Закончил круг - посчитаем время круга, скорость. Если круг был последним - зафиксируем итоговый результат и статус Finished
+	time, speed := calculateLapStats(competitor.LastLapTime, eventInfo.EventTime, float64(cm.cfg.LapLen))
+	competitor.LapTimes = append(competitor.LapTimes, time)
+	competitor.LapSpeeds = append(competitor.LapSpeeds, speed)
+	competitor.LastLapTime = eventInfo.EventTime
+	cm.checkLapSpeed(competitor, eventInfo.EventTime, speed)
+	cm.checkPenaltyLoops(competitor, eventInfo.EventTime)
+
+	cm.checkCutoff(competitor, eventInfo.EventTime)
+
+	if competitor.LapsEnded == uint(cm.cfg.Laps)-1 {
+		if competitor.FiringRangeNum < cm.cfg.FiringLines {
+			cm.applyRule(competitor, RuleMissedRange, eventInfo.EventTime)
 		}
-		competitor.Hits[targetNum] = true
-	case 7: //Закончил стрельбище - сохраним
-		competitor := cm.competitors[eventInfo.CompetitorId]
-		competitor.FiringRangeNum += 1
-	case 8: //Забежал на штрафные - запомним
-		competitor := cm.competitors[eventInfo.CompetitorId]
-		competitor.PenaltyLapsEnter = eventInfo.EventTime
-	case 9: //Выбежал со штрафных - посчитаем время, чтобы потом в final report отправить
-		competitor := cm.competitors[eventInfo.CompetitorId]
-		time := eventInfo.EventTime.Sub(competitor.PenaltyLapsEnter)
-
-		competitor.PenaltyTime += time
-	case 10: //Закончил круг - посчитаем время круга, скорость. Если круг был последним - зафиксируем итоговый результат и статус Finished
-		competitor := cm.competitors[eventInfo.CompetitorId]
-		time, speed := calculateLapStats(competitor.LastLapTime, eventInfo.EventTime, float64(cm.cfg.LapLen))
-		competitor.LapTimes = append(competitor.LapTimes, time)
-		competitor.LapSpeeds = append(competitor.LapSpeeds, speed)
-		competitor.LastLapTime = eventInfo.EventTime
-
-		if competitor.LapsEnded == uint(cm.cfg.Laps)-1 {
-			competitor.Status = "Finished"
+		if !competitor.Status.Terminal() { //Снятый правилом участник финишным статусом не перезаписывается
+			competitor.Status = StatusFinished
 			competitor.TotalTime = eventInfo.EventTime.Sub(competitor.StartTime)
-		} else if competitor.LapsEnded == uint(cm.cfg.Laps) {
-			return fmt.Errorf("competitor ended more laps than needed")
+			cm.emit(EventFinished, competitor.CompetitorId, eventInfo.EventTime, "")
 		}
-		competitor.LapsEnded += 1
-	case 11: //Ну тут просто обрабатываем, что человек не закончил гонку(статус и общее время)
-		competitor := cm.competitors[eventInfo.CompetitorId]
-
-		competitor.Status = "NotFinished"
-		competitor.TotalTime = eventInfo.EventTime.Sub(competitor.StartTime)
 	}
+	competitor.LapsEnded += 1
+	competitor.advance(StatusRacing)
+	cm.checkLapped(competitor, eventInfo.EventTime)
+	return nil
+}
+
+func (cm *CompetitionManager) handleNotFinished(competitor *Competitor, eventInfo lh.EventInfo) error { //Ну тут просто обрабатываем, что человек не закончил гонку(правило retired, по умолчанию NotFinished)
+	cm.applyRule(competitor, RuleRetired, eventInfo.EventTime)
 	return nil
 }
 
@@ -2,6 +2,7 @@ package competitionmgr
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"sort"
 	"strings"
@@ -12,7 +13,8 @@ import (
 
 type ReportInfo struct {
 	CompetitorId   int
-	Status         string
+	Status         Status
+	Reason         string //Код правила, по которому участник снят с гонки
 	TotalTime      time.Duration
 	LapTimes       []time.Duration
 	LapSpeeds      []float64
@@ -21,7 +23,7 @@ type ReportInfo struct {
 	FiringRangeNum int
 }
 
-func (cm CompetitionManager) GenerateReport() error {
+func (cm *CompetitionManager) GenerateReport() error {
 	compSlice := cm.sortedCompetitors() //Отсортируем наших получившихся участников по времени
 
 	for _, c := range compSlice { //Для каждого участника запишем report
@@ -30,53 +32,59 @@ func (cm CompetitionManager) GenerateReport() error {
 			return err
 		}
 	}
-	return nil
+	if err := cm.writeAdjustments(); err != nil { //Решения жюри по времени и протокол исправлений - после результатов
+		return err
+	}
+	return cm.writeCorrections()
 }
 
-func (cm CompetitionManager) writeCompetitorReport(c *Competitor) error {
+func (cm *CompetitionManager) writeCompetitorReport(c *Competitor) error {
 	shots := TargetsPerFiringLine * c.FiringRangeNum //Здесь просто считаются все метрики по очереди
 	penaltyHits := countHits(c.Hits)
 	penaltyMisses := shots - penaltyHits
 	penaltySpeed := computeAvgSpeed(c.PenaltyTime, float64(cm.cfg.PenaltyLen*penaltyMisses))
 
-	totalTimeStr := formatStatus(c.Status, c.TotalTime) //Здесь наши метрики форматируются в строки для вывода
-	lapsInfo := formatLapsInfo(c.LapTimes, c.LapSpeeds, cm.cfg.Laps)
-	penaltyInfo := formatLapInfo(c.PenaltyTime, penaltySpeed)
+	totalTimeStr := cm.formatStatus(c.Status, cm.resultTime(c)) //Здесь наши метрики форматируются в строки для вывода
+	lapsInfo := formatLapsInfo(c.LapTimes, c.LapSpeeds, cm.cfg.Laps, cm.lapBounds())
+	penaltyInfo := formatLapInfo(c.PenaltyTime, penaltySpeed, cm.penaltyBounds())
 	hitsInfo := fmt.Sprintf("%d/%d", penaltyHits, shots)
 
-	line := fmt.Sprintf("%s %d %s %s %s\n", //Составляем одну общую строку
+	line := fmt.Sprintf("%s %d %s %s %s", //Составляем одну общую строку
 		totalTimeStr,
 		c.CompetitorId,
 		lapsInfo,
 		penaltyInfo,
 		hitsInfo,
 	)
-	_, err := cm.outputFile.WriteString(line) //Записываем эту строку в одну строчку
+	if len(cm.adjustments) != 0 { //Колонка поправок жюри появляется, только если они есть
+		line += " " + cm.formatAdjustment(c)
+	}
+	if c.Reason != "" { //Причина снятия с гонки - последней колонкой
+		line += " " + c.Reason
+	}
+	line += "\n"
+	_, err := io.WriteString(cm.output, line) //Записываем эту строку в одну строчку
 	if err != nil {
-		return fmt.Errorf("unable to write report to output file: %v", err)
+		return cm.loc.Errorf("err.writeReport", err)
 	}
 	return nil
 }
 
-func formatStatus(status string, duration time.Duration) string { //Если статус !Finished - выводим его, иначе - выводим время
-	switch status {
-	case "NotStarted":
-		return "[NotStarted]"
-	case "NotFinished":
-		return "[NotFinished]"
-	default:
+func (cm *CompetitionManager) formatStatus(status Status, duration time.Duration) string { //Если статус !Finished - выводим его, иначе - выводим время
+	if status == StatusFinished {
 		return fmt.Sprintf("[%s]", timeParser.ConvertDurationToString(duration))
 	}
+	return fmt.Sprintf("[%s]", cm.loc.T("status."+status.String()))
 }
 
-func formatLapsInfo(times []time.Duration, speeds []float64, lapsCount int) string {
+func formatLapsInfo(times []time.Duration, speeds []float64, lapsCount int, bounds speedBounds) string {
 	var laps []string
 	timesLen := len(times)
 	for i := 0; i != lapsCount; i += 1 { //Идём по каждому кругу и форматируем его в строку, если этот круг не был пройден - он форматируется в строку {,}
 		if i < timesLen {
-			laps = append(laps, formatLapInfo(times[i], speeds[i]))
+			laps = append(laps, formatLapInfo(times[i], speeds[i], bounds))
 		} else {
-			laps = append(laps, formatLapInfo(0, 0))
+			laps = append(laps, formatLapInfo(0, 0, bounds))
 		}
 
 	}
@@ -84,11 +92,15 @@ func formatLapsInfo(times []time.Duration, speeds []float64, lapsCount int) stri
 	return fmt.Sprintf("[%s]", lapsInfo)
 }
 
-func formatLapInfo(dur time.Duration, speed float64) string { //То же, что и функцией выше, но для одного круга, а не для всех
+func formatLapInfo(dur time.Duration, speed float64, bounds speedBounds) string { //То же, что и функцией выше, но для одного круга, а не для всех
 	if dur == 0 && speed == 0 {
 		return "{,}"
 	}
-	return fmt.Sprintf("{%s, %.3f}", timeParser.ConvertDurationToString(dur), truncateFloatWithoutRounding(speed, 3))
+	mark := ""
+	if !bounds.contains(speed) { //Неправдоподобная скорость помечается в отчёте, подробности - в диагностике
+		mark = "!"
+	}
+	return fmt.Sprintf("{%s, %.3f%s}", timeParser.ConvertDurationToString(dur), truncateFloatWithoutRounding(speed, 3), mark)
 }
 
 func truncateFloatWithoutRounding(num float64, precision int) float64 { //Для вывода, как в примере в тз
@@ -104,13 +116,28 @@ func computeAvgSpeed(dur time.Duration, distanceMeters float64) float64 { //Вы
 	return 0
 }
 
-func (cm CompetitionManager) sortedCompetitors() []*Competitor { //Сортирует наших участников по времени
+func (cm *CompetitionManager) sortedCompetitors() []*Competitor { //Сортирует наших участников сначала по статусу(Status.Rank), затем по времени
+	competitors := cm.Competitors() //Берём уже упорядоченных по id, чтобы равные места всегда шли в одном порядке
+	sort.SliceStable(competitors, func(i, j int) bool {
+		a, b := competitors[i], competitors[j]
+		if a.Status.Rank() != b.Status.Rank() {
+			return a.Status.Rank() < b.Status.Rank()
+		}
+		if (a.Status == StatusLAP || a.Status == StatusDNF) && a.LapsEnded != b.LapsEnded { //Среди обойдённых и сошедших выше тот, кто успел пройти больше кругов
+			return a.LapsEnded > b.LapsEnded
+		}
+		return cm.resultTime(a) < cm.resultTime(b)
+	})
+	return competitors
+}
+
+func (cm *CompetitionManager) Competitors() []*Competitor { //Все участники по возрастанию id, нужны валидатору и другим утилитам
 	competitors := make([]*Competitor, 0, len(cm.competitors))
 	for _, c := range cm.competitors {
 		competitors = append(competitors, c)
 	}
 	sort.Slice(competitors, func(i, j int) bool {
-		return competitors[i].TotalTime < competitors[j].TotalTime
+		return competitors[i].CompetitorId < competitors[j].CompetitorId
 	})
 	return competitors
 }
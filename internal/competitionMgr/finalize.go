@@ -0,0 +1,30 @@
+package competitionmgr
+
+const SectionFinalization = "finalization"
+
+func (cm *CompetitionManager) Finalize() { //Конец потока событий: у каждого участника должен остаться окончательный статус
+	if cm.finalized {
+		return
+	}
+	cm.finalized = true
+	for _, c := range cm.Competitors() {
+		if c.Status.Terminal() {
+			continue
+		}
+		rule, fallback := RuleTooFewLaps, StatusDNF
+		if !c.Started {
+			rule, fallback = RuleNoStart, StatusDNS
+		}
+		if !cm.applyRule(c, rule, cm.lastEventTime) { //Правило выключено в конфиге, но незаконченный статус в отчёт попасть не должен
+			cm.removeFromRace(c, fallback, rule, cm.lastEventTime)
+		}
+		status := cm.loc.T("status." + c.Status.String())
+		if c.Started {
+			cm.addDiagnostic(Warning, SectionFinalization, cm.lastEventTime, c.CompetitorId, "diag.notFinished", c.LapsEnded, cm.cfg.Laps, status)
+		} else {
+			cm.addDiagnostic(Warning, SectionFinalization, cm.lastEventTime, c.CompetitorId, "diag.notStarted", status)
+		}
+	}
+	cm.checkAdjustments()
+	cm.flush()
+}
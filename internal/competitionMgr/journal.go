@@ -0,0 +1,31 @@
+package competitionmgr
+
+import (
+	"errors"
+	"fmt"
+
+	lh "yadro_test/internal/logger"
+)
+
+type EventJournal interface { //Куда записываются принятые менеджером события(например, eventstore.Store)
+	Append(eventInfo lh.EventInfo) error
+}
+
+func (cm *CompetitionManager) SetJournal(j EventJournal) {
+	cm.journal = j
+}
+
+func (cm *CompetitionManager) Replay(events []lh.EventInfo) error { //Восстановление после перезапуска: события уже в журнале и в логе, поэтому повторно их не пишем
+	cm.replaying = true
+	defer func() { cm.replaying = false }()
+	for i, e := range events {
+		err := cm.HandleEvent(e)
+		if errors.Is(err, ErrRaceClosed) {
+			return nil
+		}
+		if err != nil { //В журнале только принятые события, ошибка значит, что журнал от другой гонки или другого конфига
+			return fmt.Errorf("unable to replay journal record %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
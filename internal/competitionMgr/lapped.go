@@ -0,0 +1,15 @@
+package competitionmgr
+
+import "time"
+
+func (cm *CompetitionManager) checkLapped(competitor *Competitor, eventTime time.Time) { //Лидер закончил круг - все, кто отстал от него на целый круг, снимаются с трассы
+	if competitor.LapsEnded <= cm.leaderLaps {
+		return
+	}
+	cm.leaderLaps = competitor.LapsEnded
+	for _, c := range cm.Competitors() { //По возрастанию id, чтобы исходящие события шли в стабильном порядке
+		if c.Started && !c.Status.Terminal() && c.LapsEnded+1 < cm.leaderLaps {
+			cm.applyRule(c, RuleLapped, eventTime)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package competitionmgr
+
+import (
+	"errors"
+	"time"
+
+	lh "yadro_test/internal/logger"
+)
+
+var ErrLoopStopped = errors.New("competition manager loop is stopped")
+
+type Standing struct { //Строка текущего протокола: место только у финишировавших, у остальных 0
+	Place int
+	Competitor
+}
+
+type EventLoop struct { //Менеджер в своей горутине: всё состояние трогает только она, остальные горутины общаются с ней через каналы
+	requests chan func(cm *CompetitionManager)
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+func (cm *CompetitionManager) Run() *EventLoop { //После Run менеджер напрямую не используется, только через EventLoop
+	loop := &EventLoop{
+		requests: make(chan func(cm *CompetitionManager)),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(loop.done)
+		for {
+			select {
+			case req := <-loop.requests: //События и запросы на чтение выполняются строго по одному, в порядке поступления
+				req(cm)
+			case <-loop.quit:
+				return
+			}
+		}
+	}()
+	return loop
+}
+
+func (l *EventLoop) Do(fn func(cm *CompetitionManager)) error { //Выполнить fn в горутине менеджера и дождаться окончания, ссылки на состояние наружу из fn выносить нельзя
+	reply := make(chan struct{})
+	select {
+	case l.requests <- func(cm *CompetitionManager) {
+		defer close(reply)
+		fn(cm)
+	}:
+	case <-l.done:
+		return ErrLoopStopped
+	}
+	<-reply
+	return nil
+}
+
+func (l *EventLoop) HandleEvent(eventInfo lh.EventInfo) error {
+	var err error
+	if loopErr := l.Do(func(cm *CompetitionManager) { err = cm.HandleEvent(eventInfo) }); loopErr != nil {
+		return loopErr
+	}
+	return err
+}
+
+func (l *EventLoop) Close() error { //Закрыть гонку(финализация и отчёт), сама горутина продолжает отвечать на запросы
+	var err error
+	if loopErr := l.Do(func(cm *CompetitionManager) { err = cm.Close() }); loopErr != nil {
+		return loopErr
+	}
+	return err
+}
+
+func (l *EventLoop) AdvanceTo(now time.Time) error {
+	var err error
+	if loopErr := l.Do(func(cm *CompetitionManager) { err = cm.AdvanceTo(now) }); loopErr != nil {
+		return loopErr
+	}
+	return err
+}
+
+func (l *EventLoop) StartClock(interval time.Duration, now func() time.Time) <-chan error { //Раз в interval двигает часы гонки по now(), первая ошибка останавливает часы и уходит в канал
+	errs := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := l.AdvanceTo(timeOfDay(now()))
+				if errors.Is(err, ErrLoopStopped) {
+					return
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			case <-l.done:
+				return
+			}
+		}
+	}()
+	return errs
+}
+
+func timeOfDay(t time.Time) time.Time { //Время событий - только время суток(год 0), как его разбирает парсер
+	h, m, s := t.Clock()
+	return time.Date(0, 1, 1, h, m, s, t.Nanosecond(), time.UTC)
+}
+
+func (l *EventLoop) Standings() ([]Standing, error) {
+	var standings []Standing
+	err := l.Do(func(cm *CompetitionManager) { standings = cm.Standings() })
+	return standings, err
+}
+
+func (l *EventLoop) Competitor(competitorId int) (Competitor, bool, error) {
+	var (
+		c  Competitor
+		ok bool
+	)
+	err := l.Do(func(cm *CompetitionManager) { c, ok = cm.Competitor(competitorId) })
+	return c, ok, err
+}
+
+func (l *EventLoop) Diagnostics() ([]Diagnostic, error) {
+	var diags []Diagnostic
+	err := l.Do(func(cm *CompetitionManager) { diags = append([]Diagnostic(nil), cm.diagnostics...) })
+	return diags, err
+}
+
+func (l *EventLoop) Stop() { //Останавливает горутину, повторный вызов ничего не делает
+	select {
+	case <-l.done:
+		return
+	default:
+	}
+	select {
+	case l.quit <- struct{}{}:
+	case <-l.done:
+	}
+	<-l.done
+}
+
+func (cm *CompetitionManager) Standings() []Standing { //Текущий протокол в порядке отчёта, участники скопированы и не связаны с состоянием менеджера
+	competitors := cm.sortedCompetitors()
+	standings := make([]Standing, 0, len(competitors))
+	place := 0
+	for _, c := range competitors {
+		s := Standing{Competitor: c.clone()}
+		if c.Status == StatusFinished {
+			place += 1
+			s.Place = place
+			s.TotalTime = cm.resultTime(c) //С учётом решений жюри, как в отчёте
+		}
+		standings = append(standings, s)
+	}
+	return standings
+}
+
+func (cm *CompetitionManager) Competitor(competitorId int) (Competitor, bool) { //Копия участника со всеми служебными полями
+	c, ok := cm.competitors[competitorId]
+	if !ok {
+		return Competitor{}, false
+	}
+	return c.clone(), true
+}
+
+func (c *Competitor) clone() Competitor {
+	cp := *c
+	cp.LapTimes = append(cp.LapTimes[:0:0], c.LapTimes...)
+	cp.LapSpeeds = append(cp.LapSpeeds[:0:0], c.LapSpeeds...)
+	cp.Hits = append(cp.Hits[:0:0], c.Hits...)
+	cp.PenaltyLoops = append(cp.PenaltyLoops[:0:0], c.PenaltyLoops...)
+	return cp
+}
@@ -0,0 +1,72 @@
+package competitionmgr
+
+import (
+	"errors"
+	"time"
+
+	lh "yadro_test/internal/logger"
+)
+
+const ( //Исходящие события, которые генерирует сам менеджер
+	EventDisqualified = 32
+	EventFinished     = 33
+)
+
+var errEventIgnored = errors.New("event ignored") //Обработчик проигнорировал событие(дубликат, конфликт) - в лог оно не попадает, но и ошибкой не считается
+
+type EventObserver interface { //Получает каждое принятое и применённое событие, в том числе сгенерированные менеджером
+	LogEvent(eventInfo lh.EventInfo)
+}
+
+func (cm *CompetitionManager) AddObserver(o EventObserver) {
+	cm.observers = append(cm.observers, o)
+}
+
+func (cm *CompetitionManager) emit(eventId, competitorId int, eventTime time.Time, extraParams string) { //Исходящие события копятся и отдаются наблюдателям уже после входящего
+	cm.pending = append(cm.pending, lh.EventInfo{EventId: eventId, CompetitorId: competitorId, EventTime: eventTime, ExtraParams: extraParams})
+}
+
+func (cm *CompetitionManager) notify(eventInfo lh.EventInfo) {
+	if cm.replaying {
+		cm.pending = cm.pending[:0]
+		return
+	}
+	eventInfo.State = cm.competitorState(eventInfo.CompetitorId)
+	for _, o := range cm.observers {
+		o.LogEvent(eventInfo)
+	}
+	cm.flush()
+}
+
+func (cm *CompetitionManager) flush() { //Отдаём наблюдателям накопленные исходящие события
+	if cm.replaying {
+		cm.pending = cm.pending[:0]
+		return
+	}
+	for _, e := range cm.pending {
+		e.State = cm.competitorState(e.CompetitorId)
+		for _, o := range cm.observers {
+			o.LogEvent(e)
+		}
+	}
+	cm.pending = cm.pending[:0]
+}
+
+func (cm *CompetitionManager) competitorState(competitorId int) *lh.CompetitorState { //nil для событий без участника(например, закрытие гонки)
+	c, ok := cm.competitors[competitorId]
+	if !ok {
+		return nil
+	}
+	state := &lh.CompetitorState{
+		Status:      c.Status.String(),
+		LapsEnded:   int(c.LapsEnded),
+		TotalTime:   c.TotalTime,
+		PenaltyTime: c.PenaltyTime,
+		FiringRange: c.FiringRangeNum,
+		Hits:        countHits(c.Hits),
+	}
+	if len(c.LapTimes) != 0 {
+		state.LapTime = c.LapTimes[len(c.LapTimes)-1]
+	}
+	return state
+}
@@ -0,0 +1,46 @@
+package competitionmgr
+
+import (
+	"time"
+
+	lh "yadro_test/internal/logger"
+)
+
+const SectionPenalties = "penalties"
+
+func (cm *CompetitionManager) handleFiringRangeEnter(competitor *Competitor, eventInfo lh.EventInfo) error { //Ушёл на следующий рубеж - штрафные за предыдущий уже должны быть пройдены
+	competitor.advance(StatusRacing)
+	cm.checkPenaltyLoops(competitor, eventInfo.EventTime)
+	return nil
+}
+
+func (cm *CompetitionManager) checkPenaltyLoops(competitor *Competitor, eventTime time.Time) { //Сверяем штрафные круги с промахами на каждом рубеже, который участник уже покинул
+	for ; competitor.PenaltyChecked < competitor.FiringRangeNum; competitor.PenaltyChecked += 1 {
+		rangeIdx := competitor.PenaltyChecked
+		if (rangeIdx+1)*TargetsPerFiringLine > len(competitor.Hits) {
+			return
+		}
+		misses := TargetsPerFiringLine - countHits(competitor.Hits[rangeIdx*TargetsPerFiringLine:(rangeIdx+1)*TargetsPerFiringLine])
+		loops := 0
+		if rangeIdx < len(competitor.PenaltyLoops) {
+			loops = competitor.PenaltyLoops[rangeIdx]
+		}
+
+		expected := misses
+		if !cm.cfg.PenaltyLoopPerPair && misses > 0 { //Одна пара 8/9 покрывает все штрафные круги после рубежа
+			expected = 1
+		}
+		switch {
+		case loops < expected:
+			skipped := misses
+			if cm.cfg.PenaltyLoopPerPair {
+				skipped = expected - loops
+			}
+			competitor.PenaltySkipped += skipped
+			cm.addDiagnostic(Error, SectionPenalties, eventTime, competitor.CompetitorId, "diag.skippedPenaltyLoops", skipped, rangeIdx+1, misses, loops)
+			cm.applyRule(competitor, RuleSkippedPenalty, eventTime)
+		case loops > expected:
+			cm.addDiagnostic(Warning, SectionPenalties, eventTime, competitor.CompetitorId, "diag.extraPenaltyLoops", loops-expected, rangeIdx+1, misses, loops)
+		}
+	}
+}
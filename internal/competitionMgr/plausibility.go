@@ -0,0 +1,66 @@
+package competitionmgr
+
+import (
+	"fmt"
+	"time"
+)
+
+const SectionPlausibility = "plausibility"
+
+type speedBounds struct { //Допустимый диапазон скорости в м/с, нулевая граница не проверяется
+	min, max float64
+}
+
+func (b speedBounds) contains(speed float64) bool {
+	return (b.min <= 0 || speed >= b.min) && (b.max <= 0 || speed <= b.max)
+}
+
+func (b speedBounds) String() string {
+	switch {
+	case b.min > 0 && b.max > 0:
+		return fmt.Sprintf("%.3f-%.3f", b.min, b.max)
+	case b.min > 0:
+		return fmt.Sprintf(">=%.3f", b.min)
+	default:
+		return fmt.Sprintf("<=%.3f", b.max)
+	}
+}
+
+func (cm *CompetitionManager) lapBounds() speedBounds {
+	return speedBounds{cm.cfg.MinLapSpeed, cm.cfg.MaxLapSpeed}
+}
+
+func (cm *CompetitionManager) penaltyBounds() speedBounds {
+	return speedBounds{cm.cfg.MinPenaltySpeed, cm.cfg.MaxPenaltySpeed}
+}
+
+func (cm *CompetitionManager) checkLapSpeed(competitor *Competitor, eventTime time.Time, speed float64) { //Круг быстрее или медленнее разумного - почти наверняка потеряна или лишняя отметка
+	bounds := cm.lapBounds()
+	if !bounds.contains(speed) {
+		cm.addDiagnostic(Error, SectionPlausibility, eventTime, competitor.CompetitorId, "diag.implausibleLap", speed, len(competitor.LapSpeeds), bounds)
+	}
+}
+
+func (cm *CompetitionManager) checkPenaltySpeed(competitor *Competitor, eventTime time.Time, dur time.Duration) { //Скорость на штрафных считаем по промахам последнего рубежа: один штрафной круг на промах
+	if cm.cfg.PenaltyLoopPerPair { //Каждая пара 8/9 - ровно один штрафной круг
+		cm.checkPenaltyDistance(competitor, eventTime, dur, cm.cfg.PenaltyLen)
+		return
+	}
+	rangeIdx := competitor.FiringRangeNum - 1
+	if rangeIdx < 0 || (rangeIdx+1)*TargetsPerFiringLine > len(competitor.Hits) {
+		return
+	}
+	misses := TargetsPerFiringLine - countHits(competitor.Hits[rangeIdx*TargetsPerFiringLine:(rangeIdx+1)*TargetsPerFiringLine])
+	if misses == 0 {
+		return
+	}
+	cm.checkPenaltyDistance(competitor, eventTime, dur, cm.cfg.PenaltyLen*misses)
+}
+
+func (cm *CompetitionManager) checkPenaltyDistance(competitor *Competitor, eventTime time.Time, dur time.Duration, distance int) {
+	speed := computeAvgSpeed(dur, float64(distance))
+	bounds := cm.penaltyBounds()
+	if !bounds.contains(speed) {
+		cm.addDiagnostic(Error, SectionPlausibility, eventTime, competitor.CompetitorId, "diag.implausiblePenalty", speed, competitor.FiringRangeNum, bounds)
+	}
+}
@@ -0,0 +1,50 @@
+package competitionmgr
+
+import (
+	"fmt"
+
+	lh "yadro_test/internal/logger"
+)
+
+type EventHandler func(cm *CompetitionManager, competitor *Competitor, eventInfo lh.EventInfo) error //competitor == nil только для ещё не зарегистрированного участника
+
+type EventDef struct { //Полное описание типа события: id, схема доп. параметров и шаблон лога(lh.EventType) плюс обработчик в менеджере
+	lh.EventType
+	Validate EventHandler //Проверка события до записи в журнал, nil - проверять нечего. Handle после неё уже не должен возвращать ошибку
+	Handle   EventHandler
+}
+
+var eventHandlers = map[int]EventHandler{
+	1:  (*CompetitionManager).handleRegistration,
+	2:  (*CompetitionManager).handleDraw,
+	3:  (*CompetitionManager).handleNothing,
+	4:  (*CompetitionManager).handleStart,
+	5:  (*CompetitionManager).handleFiringRangeEnter,
+	6:  (*CompetitionManager).handleHit,
+	7:  (*CompetitionManager).handleFiringRangeLeft,
+	8:  (*CompetitionManager).handlePenaltyEnter,
+	9:  (*CompetitionManager).handlePenaltyLeft,
+	10: (*CompetitionManager).handleLapEnd,
+	11: (*CompetitionManager).handleNotFinished,
+}
+
+var eventValidators = map[int]EventHandler{ //Проверки, которые должны пройти до того, как событие попадёт в журнал и изменит состояние
+	2:  (*CompetitionManager).validateDraw,
+	4:  (*CompetitionManager).validateStart,
+	6:  (*CompetitionManager).validateHit,
+	10: (*CompetitionManager).validateLapEnd,
+}
+
+func RegisterEvent(def EventDef) error { //Клубные события(проверка инвентаря, смена лыж и тд) регистрируются здесь до начала обработки
+	if def.Handle == nil {
+		return fmt.Errorf("event id(%d) has no handler", def.Id)
+	}
+	if err := lh.RegisterEventType(def.EventType); err != nil {
+		return err
+	}
+	eventHandlers[def.Id] = def.Handle
+	if def.Validate != nil {
+		eventValidators[def.Id] = def.Validate
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package competitionmgr
+
+import (
+	"fmt"
+	"time"
+
+	timeParser "yadro_test/common"
+	"yadro_test/internal/i18n"
+)
+
+type Rule string
+
+const ( //Правила, по которым участник снимается с гонки. Какой статус даёт правило - задаётся в конфиге полем rules
+	RuleStartWindow    Rule = "startWindow"    //Стартовал вне окна startDelta от времени жеребьёвки
+	RuleRetired        Rule = "retired"        //Сам сошёл с дистанции(событие 11)
+	RuleSkippedPenalty Rule = "skippedPenalty" //Пробежал меньше штрафных кругов, чем промахов
+	RuleMissedRange    Rule = "missedRange"    //Финишировал, пропустив огневой рубеж
+	RuleTooFewLaps     Rule = "tooFewLaps"     //К концу гонки прошёл не все круги
+	RuleCutoff         Rule = "cutoff"         //Превысил maxRaceTime или не финишировал к cutoffTime
+	RuleLapped         Rule = "lapped"         //Лидер обошёл на круг(масс-старт, преследование)
+	RuleNoStart        Rule = "noStart"        //К концу гонки так и не стартовал
+)
+
+const RuleOff = "off" //Правило не применяется
+
+var ruleStatuses = map[Status]bool{ //Статусы, которые может выставить правило
+	StatusLAP: true,
+	StatusDNF: true,
+	StatusDSQ: true,
+	StatusDNS: true,
+}
+
+var defaultRules = map[Rule]string{ //По умолчанию правила повторяют прежнее поведение, остальные включаются в конфиге
+	RuleStartWindow:    "DNS",
+	RuleRetired:        "DNF",
+	RuleSkippedPenalty: RuleOff,
+	RuleMissedRange:    RuleOff,
+	RuleTooFewLaps:     "DNF",
+	RuleCutoff:         "DNF", //Пока не заданы maxRaceTime или cutoffTime, правило не срабатывает
+	RuleLapped:         RuleOff,
+	RuleNoStart:        "DNS",
+}
+
+func parseRuleStatus(code string) (Status, bool) { //false - правило выключено
+	var status Status
+	if code == RuleOff || status.UnmarshalText([]byte(code)) != nil || !ruleStatuses[status] {
+		return 0, false
+	}
+	return status, true
+}
+
+func ValidateRules(rules map[string]string) error { //Проверяем конфиг при запуске, чтобы опечатка не выключила правило молча
+	for rule, status := range rules {
+		if _, ok := defaultRules[Rule(rule)]; !ok {
+			return i18n.Errorf("err.unknownRule", rule)
+		}
+		if _, ok := parseRuleStatus(status); !ok && status != RuleOff {
+			return i18n.Errorf("err.invalidRuleStatus", status, rule)
+		}
+	}
+	return nil
+}
+
+func buildRules(overrides map[string]string) map[Rule]string {
+	rules := make(map[Rule]string, len(defaultRules))
+	for rule, status := range defaultRules {
+		rules[rule] = status
+	}
+	for rule, status := range overrides {
+		rules[Rule(rule)] = status
+	}
+	return rules
+}
+
+func (cm *CompetitionManager) applyRule(competitor *Competitor, rule Rule, eventTime time.Time) bool { //Возвращает true, если правило сняло участника с гонки
+	status, ok := parseRuleStatus(cm.rules[rule])
+	if !ok || competitor.Status.Terminal() { //Правило выключено или у участника уже окончательный статус - первое срабатывание остаётся
+		return false
+	}
+	cm.removeFromRace(competitor, status, rule, eventTime)
+	return true
+}
+
+func (cm *CompetitionManager) removeFromRace(competitor *Competitor, status Status, rule Rule, eventTime time.Time) {
+	competitor.Status = status
+	competitor.Reason = string(rule)
+	competitor.TotalTime = 0
+	if competitor.Started {
+		competitor.TotalTime = eventTime.Sub(competitor.StartTime)
+	}
+	cm.emit(EventDisqualified, competitor.CompetitorId, eventTime, fmt.Sprintf("%s %s", status, rule))
+}
+
+func (cm *CompetitionManager) checkCutoff(competitor *Competitor, eventTime time.Time) {
+	if cm.cfg.MaxRaceTime == "" {
+		return
+	}
+	maxRaceTime, _ := timeParser.ConvertStringToDuration(cm.cfg.MaxRaceTime) //Проверено в validateLapEnd
+	if eventTime.Sub(competitor.StartTime) > maxRaceTime {
+		cm.applyRule(competitor, RuleCutoff, eventTime)
+	}
+}
+
+func (cm *CompetitionManager) cutoffDue(now time.Time) (time.Time, bool, error) { //true - наступил cutoffTime, а участников на трассе ещё не классифицировали
+	if cm.cfg.CutoffTime == "" || cm.cutoffReached {
+		return time.Time{}, false, nil
+	}
+	cutoff, err := timeParser.ConvertStringToTime(cm.cfg.CutoffTime)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return cutoff, now.After(cutoff), nil
+}
+
+func (cm *CompetitionManager) sweepCutoff(cutoff time.Time) { //После cutoffTime все, кто ещё на трассе, классифицируются по правилу cutoff
+	cm.cutoffReached = true
+	for _, c := range cm.Competitors() {
+		if c.Started && !c.Status.Terminal() {
+			cm.applyRule(c, RuleCutoff, cutoff)
+		}
+	}
+}
+
+func (cm *CompetitionManager) AdvanceTo(now time.Time) error { //Часы гонки без входящего события: иначе cutoffTime и closeTime срабатывают только по следующему событию
+	if cm.closed {
+		return nil
+	}
+	if closed, err := cm.checkCloseTime(now); closed || err != nil { //Тот же порядок, что и у входящего события
+		return err
+	}
+	cutoff, due, err := cm.cutoffDue(now)
+	if err != nil || !due {
+		return err
+	}
+	cm.sweepCutoff(cutoff)
+	cm.flush()
+	return nil
+}
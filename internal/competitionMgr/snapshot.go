@@ -0,0 +1,118 @@
+package competitionmgr
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"yadro_test/internal/cfg"
+	"yadro_test/internal/i18n"
+)
+
+const SnapshotVersion = 1 //Увеличиваем при любом несовместимом изменении состояния менеджера
+
+type Snapshot struct { //Полное состояние менеджера: по нему гонку можно продолжить на другой машине
+	Version       int           `json:"version"`
+	Config        *cfg.Config   `json:"config"`
+	Events        int           `json:"events"` //Сколько событий входного потока уже обработано
+	Competitors   []*Competitor `json:"competitors"`
+	SeenEvents    []SeenEvent   `json:"seenEvents"`
+	Diagnostics   []Diagnostic  `json:"diagnostics"`
+	LastEventTime time.Time     `json:"lastEventTime"`
+	LeaderLaps    uint          `json:"leaderLaps"`
+	CutoffReached bool          `json:"cutoffReached"`
+	Finalized     bool          `json:"finalized"`
+	Closed        bool          `json:"closed"`
+	ClosedAt      time.Time     `json:"closedAt"`
+}
+
+type SeenEvent struct {
+	EventId      int       `json:"event"`
+	CompetitorId int       `json:"competitor"`
+	EventTime    time.Time `json:"time"`
+	ExtraParams  string    `json:"extra,omitempty"`
+}
+
+func (cm *CompetitionManager) EventCount() int {
+	return cm.eventCount
+}
+
+func (cm *CompetitionManager) Snapshot() *Snapshot {
+	seen := make([]SeenEvent, 0, len(cm.seenEvents))
+	for k := range cm.seenEvents {
+		seen = append(seen, SeenEvent{k.eventId, k.competitorId, k.eventTime, k.extraParams})
+	}
+	sort.Slice(seen, func(i, j int) bool { //Порядок мапы случаен, а одинаковое состояние должно давать одинаковый снимок
+		a, b := seen[i], seen[j]
+		if !a.EventTime.Equal(b.EventTime) {
+			return a.EventTime.Before(b.EventTime)
+		}
+		if a.CompetitorId != b.CompetitorId {
+			return a.CompetitorId < b.CompetitorId
+		}
+		if a.EventId != b.EventId {
+			return a.EventId < b.EventId
+		}
+		return a.ExtraParams < b.ExtraParams
+	})
+	return &Snapshot{
+		Version:       SnapshotVersion,
+		Config:        cm.cfg,
+		Events:        cm.eventCount,
+		Competitors:   cm.Competitors(),
+		SeenEvents:    seen,
+		Diagnostics:   cm.diagnostics,
+		LastEventTime: cm.lastEventTime,
+		LeaderLaps:    cm.leaderLaps,
+		CutoffReached: cm.cutoffReached,
+		Finalized:     cm.finalized,
+		Closed:        cm.closed,
+		ClosedAt:      cm.closedAt,
+	}
+}
+
+func (cm *CompetitionManager) WriteSnapshot(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cm.Snapshot()); err != nil {
+		return cm.loc.Errorf("err.writeSnapshot", err)
+	}
+	return nil
+}
+
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, i18n.Errorf("err.readSnapshot", err)
+	}
+	if s.Version != SnapshotVersion {
+		return nil, i18n.Errorf("err.snapshotVersion", s.Version, SnapshotVersion)
+	}
+	if s.Config == nil {
+		return nil, i18n.Errorf("err.snapshotNoConfig")
+	}
+	if err := ValidateRules(s.Config.Rules); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Snapshot) Restore(output io.Writer) *CompetitionManager { //Наблюдателей и журнал после восстановления подключает вызывающий
+	cm := NewCompetitionManager(output, s.Config)
+	for _, c := range s.Competitors {
+		cm.competitors[c.CompetitorId] = c
+	}
+	for _, e := range s.SeenEvents {
+		cm.seenEvents[eventKey{e.EventId, e.CompetitorId, e.EventTime, e.ExtraParams}] = struct{}{}
+	}
+	cm.diagnostics = s.Diagnostics
+	cm.eventCount = s.Events
+	cm.lastEventTime = s.LastEventTime
+	cm.leaderLaps = s.LeaderLaps
+	cm.cutoffReached = s.CutoffReached
+	cm.finalized = s.Finalized
+	cm.closed = s.Closed
+	cm.closedAt = s.ClosedAt
+	return cm
+}
@@ -0,0 +1,77 @@
+package competitionmgr
+
+import "fmt"
+
+type Status int
+
+const ( //Статусы идут в порядке жизненного цикла участника, нулевое значение - только что зарегистрирован
+	StatusRegistered Status = iota
+	StatusDrawn             //Получил стартовое время по жеребьёвке
+	StatusStarted           //Стартовал
+	StatusRacing            //На трассе: прошёл рубеж или круг
+	StatusFinished          //Прошёл все круги - дальше статусы окончательные
+	StatusLAP               //Снят с трассы, обойдён лидером на круг
+	StatusDNF               //Не финишировал
+	StatusDSQ               //Дисквалифицирован
+	StatusDNS               //Не стартовал
+)
+
+var statusNames = [...]string{"Registered", "Drawn", "Started", "Racing", "Finished", "LAP", "DNF", "DSQ", "DNS"}
+
+var statusRanks = [...]int{ //Порядок в итоговом протоколе: финишировавшие, обойдённые, сошедшие, дисквалифицированные, не стартовавшие,
+	// а незавершённые статусы(после финализации их быть не должно) - в самом конце
+	StatusFinished:   0,
+	StatusLAP:        1,
+	StatusDNF:        2,
+	StatusDSQ:        3,
+	StatusDNS:        4,
+	StatusRacing:     5,
+	StatusStarted:    6,
+	StatusDrawn:      7,
+	StatusRegistered: 8,
+}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+	return statusNames[s]
+}
+
+func (s Status) valid() bool {
+	return s >= 0 && int(s) < len(statusNames)
+}
+
+func (s Status) Terminal() bool { //Окончательный статус события участника уже не меняют
+	return s >= StatusFinished && s.valid()
+}
+
+func (s Status) Rank() int {
+	if !s.valid() {
+		return len(statusRanks)
+	}
+	return statusRanks[s]
+}
+
+func (s Status) MarshalText() ([]byte, error) {
+	if !s.valid() {
+		return nil, fmt.Errorf("unknown status(%d)", int(s))
+	}
+	return []byte(statusNames[s]), nil
+}
+
+func (s *Status) UnmarshalText(text []byte) error {
+	for i, name := range statusNames {
+		if name == string(text) {
+			*s = Status(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status(%s)", text)
+}
+
+func (c *Competitor) advance(status Status) { //Продвигаем участника по жизненному циклу, окончательный статус не трогаем
+	if !c.Status.Terminal() && status > c.Status {
+		c.Status = status
+	}
+}
@@ -0,0 +1,140 @@
+package corrections
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	lh "yadro_test/internal/logger"
+)
+
+type Op string
+
+const (
+	OpVoid    Op = "void"    //Событие N не учитывается
+	OpReplace Op = "replace" //Событие N заменяется на event
+	OpInsert  Op = "insert"  //event вставляется после события N(0 - в самое начало)
+)
+
+type Correction struct { //Одна строка файла исправлений, например {"op":"replace","seq":12,"event":"[10:05:01.000] 6 1 4","by":"jury","reason":"wrong target"}
+	Op     Op     `json:"op"`
+	Seq    int    `json:"seq"`             //Номер события в исходном потоке, с 1
+	Event  string `json:"event,omitempty"` //Новое событие в любом из входных форматов
+	By     string `json:"by"`              //Кто внёс исправление
+	Reason string `json:"reason,omitempty"`
+}
+
+type AuditEntry struct { //Что было исправлено, кем и каких участников это затронуло
+	Correction
+	Original *lh.EventInfo //nil для вставки
+	New      *lh.EventInfo //nil для аннулирования
+	Affected []int
+}
+
+func Parse(r io.Reader) ([]Correction, error) {
+	var corrections []Correction
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum += 1 {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var c Correction
+		if err := json.Unmarshal([]byte(line), &c); err != nil {
+			return nil, fmt.Errorf("invalid correction on line %d: %v", lineNum, err)
+		}
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("invalid correction on line %d: %v", lineNum, err)
+		}
+		corrections = append(corrections, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read corrections: %v", err)
+	}
+	return corrections, nil
+}
+
+func (c Correction) validate() error {
+	switch c.Op {
+	case OpVoid:
+		if c.Event != "" {
+			return fmt.Errorf("void can`t have an event")
+		}
+	case OpReplace, OpInsert:
+		if c.Event == "" {
+			return fmt.Errorf("%s needs an event", c.Op)
+		}
+	default:
+		return fmt.Errorf("unknown op(%s), expected void, replace or insert", c.Op)
+	}
+	if c.By == "" { //Анонимных исправлений в протоколе быть не должно
+		return fmt.Errorf("missing author(by)")
+	}
+	return nil
+}
+
+type slot struct {
+	event    *lh.EventInfo //nil - событие аннулировано
+	touched  bool          //Уже исправлено: второй раз то же событие не правим, чтобы не запутать протокол
+	inserted []lh.EventInfo
+}
+
+func Apply(events []lh.EventInfo, corrections []Correction, parser *lh.Parser) ([]lh.EventInfo, []AuditEntry, error) { //Исправления применяются по порядку поверх исходного потока, номера событий всегда исходные
+	slots := make([]slot, len(events)+1) //slots[0] - только для вставки в начало
+	for i := range events {
+		slots[i+1].event = &events[i]
+	}
+
+	audit := make([]AuditEntry, 0, len(corrections))
+	for i, c := range corrections {
+		if c.Seq < 0 || c.Seq > len(events) || (c.Seq == 0 && c.Op != OpInsert) {
+			return nil, nil, fmt.Errorf("correction %d: event #%d does not exist", i+1, c.Seq)
+		}
+		entry := AuditEntry{Correction: c}
+		if c.Event != "" {
+			newEvent, err := parser.ParseLine(c.Event)
+			if err != nil {
+				return nil, nil, fmt.Errorf("correction %d: %w", i+1, err)
+			}
+			entry.New = &newEvent
+		}
+
+		s := &slots[c.Seq]
+		switch c.Op {
+		case OpVoid, OpReplace:
+			if s.touched {
+				return nil, nil, fmt.Errorf("correction %d: event #%d is already corrected", i+1, c.Seq)
+			}
+			s.touched = true
+			entry.Original = s.event
+			s.event = entry.New
+		case OpInsert:
+			s.inserted = append(s.inserted, *entry.New)
+		}
+		entry.Affected = affected(entry.Original, entry.New)
+		audit = append(audit, entry)
+	}
+
+	corrected := make([]lh.EventInfo, 0, len(events))
+	for _, s := range slots {
+		if s.event != nil {
+			corrected = append(corrected, *s.event)
+		}
+		corrected = append(corrected, s.inserted...)
+	}
+	return corrected, audit, nil
+}
+
+func affected(events ...*lh.EventInfo) []int {
+	var ids []int
+	for _, e := range events {
+		if e != nil && (len(ids) == 0 || ids[0] != e.CompetitorId) {
+			ids = append(ids, e.CompetitorId)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,131 @@
+package corrections
+
+import (
+	"strings"
+	"testing"
+
+	lh "yadro_test/internal/logger"
+)
+
+func parseEvents(t *testing.T, lines ...string) []lh.EventInfo {
+	t.Helper()
+	parser := lh.NewParser(nil)
+	events := make([]lh.EventInfo, 0, len(lines))
+	for _, line := range lines {
+		e, err := parser.ParseLine(line)
+		if err != nil {
+			t.Fatalf("ParseLine(%q) failed: %v", line, err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr string
+	}{
+		{"all ops", `{"op":"void","seq":1,"by":"jury"}
+
+{"op":"replace","seq":2,"event":"[10:00:00.000] 4 1","by":"jury","reason":"late start"}
+{"op":"insert","seq":0,"event":"[09:00:00.000] 1 3","by":"secretary"}`, 3, ""},
+		{"unknown op", `{"op":"delete","seq":1,"by":"jury"}`, 0, "unknown op(delete)"},
+		{"void with event", `{"op":"void","seq":1,"event":"[10:00:00.000] 4 1","by":"jury"}`, 0, "void can`t have an event"},
+		{"replace without event", `{"op":"replace","seq":1,"by":"jury"}`, 0, "replace needs an event"},
+		{"no author", `{"op":"void","seq":1}`, 0, "missing author(by)"},
+		{"broken json", `{"op":`, 0, "invalid correction on line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("Parse() returned %d corrections, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	events := parseEvents(t,
+		"[09:00:00.000] 1 1",
+		"[09:00:01.000] 1 2",
+		"[10:00:00.000] 4 1",
+		"[10:01:00.000] 4 2",
+	)
+	corrections := []Correction{
+		{Op: OpVoid, Seq: 2, By: "jury", Reason: "not registered"},
+		{Op: OpReplace, Seq: 4, Event: "[10:01:00.000] 4 1", By: "jury"},
+		{Op: OpInsert, Seq: 0, Event: "[08:59:00.000] 1 3", By: "secretary"},
+		{Op: OpInsert, Seq: 3, Event: "[10:00:30.000] 4 3", By: "secretary"},
+	}
+	got, audit, err := Apply(events, corrections, lh.NewParser(nil))
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	want := []string{
+		"[08:59:00.000] 1 3",
+		"[09:00:00.000] 1 1",
+		"[10:00:00.000] 4 1",
+		"[10:00:30.000] 4 3",
+		"[10:01:00.000] 4 1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Apply() returned %d events, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if line := (lh.TextFormat{}).Format(got[i]); line != w {
+			t.Errorf("event %d = %q, want %q", i, line, w)
+		}
+	}
+
+	if len(audit) != len(corrections) {
+		t.Fatalf("Apply() returned %d audit entries, want %d", len(audit), len(corrections))
+	}
+	if audit[0].Original == nil || audit[0].New != nil || audit[0].Affected[0] != 2 {
+		t.Errorf("void audit = %+v", audit[0])
+	}
+	if a := audit[1]; a.Original == nil || a.New == nil || len(a.Affected) != 2 || a.Affected[0] != 1 || a.Affected[1] != 2 {
+		t.Errorf("replace audit = %+v, want competitors 1 and 2 affected", a)
+	}
+	if audit[2].Original != nil || audit[2].Affected[0] != 3 {
+		t.Errorf("insert audit = %+v", audit[2])
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	events := parseEvents(t, "[09:00:00.000] 1 1", "[09:00:01.000] 1 2")
+	tests := []struct {
+		name        string
+		corrections []Correction
+		wantErr     string
+	}{
+		{"no such event", []Correction{{Op: OpVoid, Seq: 3, By: "jury"}}, "event #3 does not exist"},
+		{"void at start", []Correction{{Op: OpVoid, Seq: 0, By: "jury"}}, "event #0 does not exist"},
+		{"corrected twice", []Correction{
+			{Op: OpVoid, Seq: 1, By: "jury"},
+			{Op: OpReplace, Seq: 1, Event: "[09:00:00.000] 1 3", By: "jury"},
+		}, "correction 2: event #1 is already corrected"},
+		{"malformed event", []Correction{{Op: OpInsert, Seq: 1, Event: "garbage", By: "jury"}}, "correction 1:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Apply(events, tt.corrections, lh.NewParser(nil))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Apply() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
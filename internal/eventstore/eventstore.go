@@ -0,0 +1,129 @@
+package eventstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	lh "yadro_test/internal/logger"
+)
+
+const timeLayout = "15:04:05.000"
+
+type record struct { //Одна строка файла: "<crc32 в hex> <json>"
+	Seq          int    `json:"seq"`
+	EventId      int    `json:"event"`
+	CompetitorId int    `json:"competitor"`
+	Time         string `json:"time"`
+	Extra        string `json:"extra,omitempty"`
+}
+
+type Store struct { //Журнал принятых на вход событий: только дописывается, каждая запись с контрольной суммой
+	f      *os.File
+	events []lh.EventInfo
+}
+
+func Open(path string) (*Store, error) { //Открывает или создаёт журнал и читает уже сохранённые события
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{f: f}
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	var offset int64 //Конец последней целой записи
+	reader := bufio.NewReader(s.f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 { //Запись оборвалась на середине(упали во время записи) - отрезаем хвост
+				return s.truncate(offset)
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read event store: %v", err)
+		}
+
+		eventInfo, err := decode(bytes.TrimSuffix(line, []byte("\n")))
+		if err != nil {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF { //Битая последняя запись - тоже недописанный хвост
+				return s.truncate(offset)
+			}
+			return fmt.Errorf("event store is corrupted at record %d: %v", len(s.events)+1, err)
+		}
+		s.events = append(s.events, eventInfo)
+		offset += int64(len(line))
+	}
+	_, err := s.f.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (s *Store) truncate(offset int64) error {
+	if err := s.f.Truncate(offset); err != nil {
+		return fmt.Errorf("unable to truncate event store: %v", err)
+	}
+	_, err := s.f.Seek(offset, io.SeekStart)
+	return err
+}
+
+func decode(line []byte) (lh.EventInfo, error) {
+	sum, data, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		return lh.EventInfo{}, fmt.Errorf("missing checksum")
+	}
+	if fmt.Sprintf("%08x", crc32.ChecksumIEEE(data)) != string(sum) {
+		return lh.EventInfo{}, fmt.Errorf("checksum mismatch")
+	}
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return lh.EventInfo{}, err
+	}
+	eventTime, err := time.Parse(timeLayout, r.Time)
+	if err != nil {
+		return lh.EventInfo{}, fmt.Errorf("invalid time(%s)", r.Time)
+	}
+	return lh.EventInfo{EventId: r.EventId, CompetitorId: r.CompetitorId, EventTime: eventTime, ExtraParams: r.Extra}, nil
+}
+
+func (s *Store) Events() []lh.EventInfo { //Сохранённые события в порядке записи, доп. параметры менеджер разберёт заново
+	return s.events
+}
+
+func (s *Store) Append(eventInfo lh.EventInfo) error { //Возвращается только после того, как запись дошла до диска
+	data, err := json.Marshal(record{
+		Seq:          len(s.events) + 1,
+		EventId:      eventInfo.EventId,
+		CompetitorId: eventInfo.CompetitorId,
+		Time:         eventInfo.EventTime.Format(timeLayout),
+		Extra:        eventInfo.ExtraParams,
+	})
+	if err != nil {
+		return err
+	}
+	line := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(data), data)
+	if _, err := s.f.WriteString(line); err != nil {
+		return fmt.Errorf("unable to write event store: %v", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("unable to sync event store: %v", err)
+	}
+	eventInfo.Payload = nil
+	s.events = append(s.events, eventInfo)
+	return nil
+}
+
+func (s *Store) Close() error {
+	return s.f.Close()
+}
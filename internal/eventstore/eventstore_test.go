@@ -0,0 +1,108 @@
+package eventstore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	lh "yadro_test/internal/logger"
+)
+
+func testEvents() []lh.EventInfo {
+	base := time.Date(0, 1, 1, 9, 30, 0, 0, time.UTC)
+	return []lh.EventInfo{
+		{EventId: 1, CompetitorId: 1, EventTime: base},
+		{EventId: 2, CompetitorId: 1, EventTime: base.Add(time.Second), ExtraParams: "10:00:00.000"},
+		{EventId: 11, CompetitorId: 1, EventTime: base.Add(time.Hour + 1500*time.Millisecond), ExtraParams: "Lost in the forest"},
+	}
+}
+
+func TestAppendAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for _, e := range testEvents() {
+		if err := s.Append(e); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	s.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+	if !reflect.DeepEqual(s.Events(), testEvents()) {
+		t.Errorf("Events() = %v, want %v", s.Events(), testEvents())
+	}
+
+	if err := s.Append(testEvents()[0]); err != nil { //После переоткрытия запись продолжается в конец
+		t.Fatalf("Append() error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 4 {
+		t.Errorf("Expected 4 records, got %d", lines)
+	}
+}
+
+func TestTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for _, e := range testEvents()[:2] {
+		s.Append(e)
+	}
+	s.Close()
+	data, _ := os.ReadFile(path)
+
+	tests := []struct {
+		name string
+		tail string
+	}{
+		{"unfinished record", `1a2b3c4d {"seq":3,"ev`},
+		{"bad checksum", "00000000 {\"seq\":3,\"event\":1,\"competitor\":2,\"time\":\"09:30:00.000\"}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.WriteFile(path, append(append([]byte{}, data...), tt.tail...), 0644)
+			s, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer s.Close()
+			if len(s.Events()) != 2 {
+				t.Errorf("Expected 2 events, got %d", len(s.Events()))
+			}
+			if got, _ := os.ReadFile(path); string(got) != string(data) {
+				t.Errorf("Torn tail was not truncated: %q", got)
+			}
+		})
+	}
+}
+
+func TestCorruptedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for _, e := range testEvents() {
+		s.Append(e)
+	}
+	s.Close()
+
+	data, _ := os.ReadFile(path)
+	corrupted := strings.Replace(string(data), `"competitor":1`, `"competitor":7`, 1) //Запись в середине файла испорчена - это уже не недописанный хвост
+	os.WriteFile(path, []byte(corrupted), 0644)
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "corrupted at record 1") {
+		t.Errorf("Open() error = %v, want corrupted record error", err)
+	}
+}
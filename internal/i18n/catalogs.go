@@ -0,0 +1,242 @@
+package i18n
+
+var catalogs = map[Lang]map[string]string{ //В шаблонах событий {competitor} и {extra} подставляет логгер, в остальных сообщениях обычные fmt-глаголы
+	En: {
+		"event.1":  "The competitor({competitor}) registered",
+		"event.2":  "The start time for the competitor({competitor}) was set by a draw to {extra}",
+		"event.3":  "The competitor({competitor}) is on the start line",
+		"event.4":  "The competitor({competitor}) has started",
+		"event.5":  "The competitor({competitor}) is on the firing range({extra})",
+		"event.6":  "The target({extra}) has been hit by competitor({competitor})",
+		"event.7":  "The competitor({competitor}) left the firing range",
+		"event.8":  "The competitor({competitor}) entered the penalty laps",
+		"event.9":  "The competitor({competitor}) left the penalty laps",
+		"event.10": "The competitor({competitor}) ended the main lap",
+		"event.11": "The competitor({competitor}) can`t continue: {extra}",
+		"event.12": "The race was closed",
+		"event.32": "The competitor({competitor}) is disqualified({extra})",
+		"event.33": "The competitor({competitor}) has finished",
+
+		"event.unknown": "Unknown event(%d) for competitor(%d)",
+
+		"status.DNS":        "NotStarted",
+		"status.DNF":        "NotFinished",
+		"status.Finished":   "Finished",
+		"status.Registered": "Registered",
+		"status.Drawn":      "Drawn",
+		"status.Started":    "Started",
+		"status.Racing":     "Racing",
+		"status.DSQ":        "Disqualified",
+		"status.LAP":        "Lapped",
+
+		"diagnostics.header":     "Diagnostics: %d warnings, %d errors",
+		"diagnostics.warning":    "WARNING",
+		"diagnostics.error":      "ERROR",
+		"diagnostics.competitor": "competitor(%d)",
+		"section.duplicates":     "duplicates",
+		"section.conflicts":      "conflicts",
+		"section.plausibility":   "plausibility",
+		"section.penalties":      "penalty laps",
+		"section.finalization":   "finalization",
+		"section.adjustments":    "adjustments",
+
+		"diag.duplicateEvent":       "duplicate event %d ignored",
+		"diag.repeatedRegistration": "repeated registration ignored",
+		"diag.repeatedDraw":         "repeated draw to %s ignored",
+		"diag.conflictingDraw":      "conflicting draw to %s ignored, start time already set to %s",
+		"diag.implausibleLap":       "impossible speed %.3f m/s on lap %d(allowed %s), a timing event is probably missing",
+		"diag.notStarted":           "never started, classified as %s",
+		"diag.notFinished":          "did not finish(%d of %d laps ended), classified as %s",
+		"diag.unknownAdjustment":    "adjustment %s for an unregistered competitor is ignored",
+		"diag.ignoredAdjustment":    "adjustment %s is ignored, competitor is %s",
+		"diag.skippedPenaltyLoops":  "skipped %d penalty laps after firing range %d(%d misses, %d penalty laps entries)",
+		"diag.extraPenaltyLoops":    "%d extra penalty laps after firing range %d(%d misses, %d penalty laps entries)",
+		"diag.implausiblePenalty":   "impossible penalty laps speed %.3f m/s after firing range %d(allowed %s), a timing event is probably missing",
+
+		"err.notRegistered":      "competitor(%d) is not registered",
+		"err.unknownEvent":       "unknown event id(%d)",
+		"err.unexpectedPayload":  "unexpected payload %T for event %d",
+		"err.targetOutOfRange":   "target num can`t be more than %d(got %d)",
+		"err.tooManyLaps":        "competitor ended more laps than needed",
+		"err.writeReport":        "unable to write report to output file: %v",
+		"err.writeDiagnostics":   "unable to write diagnostics: %v",
+		"err.raceClosed":         "race was closed at %s, no more events are accepted",
+		"err.unknownFormat":      "unknown input format(%s)",
+		"err.fewParams":          "insufficient number of parameters in line (%s)",
+		"err.eventIdNotInt":      "can`t convert eventId(%s) to int",
+		"err.competitorIdNotInt": "can`t convert competitorId(%s) to int",
+		"err.invalidJSON":        "invalid json in line (%s): %v",
+		"err.invalidTime":        "unable to parse time.Time(%s)",
+		"err.invalidDraw":        "invalid draw time(%s) for event 2",
+		"err.invalidFiringRange": "invalid firing range(%s) for event 5",
+		"err.invalidTarget":      "invalid target(%s) for event 6, expected number from 1 to %d",
+		"err.missingReason":      "missing reason for event 11",
+		"err.outgoingEvent":      "event id(%d) is generated by the system and can`t be in the input",
+		"err.unexpectedParams":   "unexpected extra params(%s) for event %d",
+		"err.unknownRule":        "unknown rule(%s)",
+		"err.invalidRuleStatus":  "invalid status(%s) for rule %s, expected DNS, DNF, DSQ, LAP or off",
+		"err.writeSnapshot":      "unable to write snapshot: %v",
+		"err.readSnapshot":       "unable to read snapshot: %v",
+		"err.snapshotVersion":    "unsupported snapshot version(%d), expected %d",
+		"err.snapshotNoConfig":   "snapshot has no config",
+		"err.deltaSign":          "delta(%s) must start with + or -",
+		"err.invalidAdjustment":  "invalid adjustment on line %d: %v",
+		"err.adjustmentNoReason": "invalid adjustment on line %d: missing reason",
+		"err.readAdjustments":    "unable to read adjustments: %v",
+		"err.noRaceId":           "race id is required",
+		"err.raceExists":         "race(%s) is already registered",
+		"err.unknownRace":        "unknown race(%s)",
+		"err.race":               "race(%s): %v",
+		"err.noRaceRoute":        "line without race id(%s)",
+		"err.racesFileNotFound":  "races file not found in %s",
+		"err.readRacesFile":      "failed to read races file %s: %v",
+		"err.noRaces":            "races file %s has no races",
+
+		"report.adjustments": "Adjustments:",
+		"report.adjustment":  "%d. competitor(%d) %s: %s",
+		"report.corrections": "Corrections:",
+		"report.void":        "%d. event #%d %s voided by %s, affected competitors: %s",
+		"report.replace":     "%d. event #%d %s replaced with %s by %s, affected competitors: %s",
+		"report.insert":      "%d. event %[3]s inserted after #%[2]d by %[4]s, affected competitors: %[5]s",
+
+		"validate.malformedLine":     "malformed line: %s",
+		"validate.notChronological":  "events are not in chronological order",
+		"validate.unknownCompetitor": "event %d for unknown competitor",
+		"validate.illegalSequence":   "illegal sequence: event %d after event %d: %s",
+		"validate.alreadyFinished":   "competitor has already finished or retired",
+		"validate.drawAfterStart":    "draw after start",
+		"validate.notDrawn":          "start time was not drawn",
+		"validate.alreadyStarted":    "competitor has already started",
+		"validate.notStarted":        "competitor has not started",
+		"validate.alreadyOnRange":    "competitor is already on the firing range or penalty laps",
+		"validate.notOnRange":        "competitor is not on the firing range",
+		"validate.penaltyEnter":      "penalty laps can only be entered after leaving the firing range",
+		"validate.notInPenalty":      "competitor is not on the penalty laps",
+		"validate.lapEndOnRange":     "lap can`t end on the firing range or penalty laps",
+		"validate.rangeVisits":       "finished with %d firing range visits, expected %d",
+		"validate.missingFinish":     "missing finish event: %d of %d laps ended",
+		"validate.file":              "file",
+		"validate.line":              "line %d",
+		"validate.problems":          "%d problems found",
+		"validate.readEvents":        "unable to read events: %v",
+	},
+	Ru: {
+		"event.1":  "Участник({competitor}) зарегистрирован",
+		"event.2":  "Участнику({competitor}) по жеребьёвке назначено время старта {extra}",
+		"event.3":  "Участник({competitor}) на линии старта",
+		"event.4":  "Участник({competitor}) стартовал",
+		"event.5":  "Участник({competitor}) на огневом рубеже({extra})",
+		"event.6":  "Мишень({extra}) поражена участником({competitor})",
+		"event.7":  "Участник({competitor}) покинул огневой рубеж",
+		"event.8":  "Участник({competitor}) ушёл на штрафные круги",
+		"event.9":  "Участник({competitor}) закончил штрафные круги",
+		"event.10": "Участник({competitor}) закончил основной круг",
+		"event.11": "Участник({competitor}) не может продолжить: {extra}",
+		"event.12": "Гонка закрыта",
+		"event.32": "Участник({competitor}) дисквалифицирован({extra})",
+		"event.33": "Участник({competitor}) финишировал",
+
+		"event.unknown": "Неизвестное событие(%d) для участника(%d)",
+
+		"status.DNS":        "НеСтартовал",
+		"status.DNF":        "НеФинишировал",
+		"status.Finished":   "Финишировал",
+		"status.Registered": "Зарегистрирован",
+		"status.Drawn":      "ПолучилСтарт",
+		"status.Started":    "Стартовал",
+		"status.Racing":     "НаТрассе",
+		"status.DSQ":        "Дисквалифицирован",
+		"status.LAP":        "ОбойдёнНаКруг",
+
+		"diagnostics.header":     "Диагностика: предупреждений - %d, ошибок - %d",
+		"diagnostics.warning":    "ПРЕДУПРЕЖДЕНИЕ",
+		"diagnostics.error":      "ОШИБКА",
+		"diagnostics.competitor": "участник(%d)",
+		"section.duplicates":     "дубликаты",
+		"section.conflicts":      "конфликты",
+		"section.plausibility":   "правдоподобность",
+		"section.penalties":      "штрафные круги",
+		"section.finalization":   "итоговая классификация",
+		"section.adjustments":    "решения жюри",
+
+		"diag.duplicateEvent":       "повторное событие %d проигнорировано",
+		"diag.repeatedRegistration": "повторная регистрация проигнорирована",
+		"diag.repeatedDraw":         "повторная жеребьёвка на %s проигнорирована",
+		"diag.conflictingDraw":      "противоречащая жеребьёвка на %s проигнорирована, время старта уже назначено на %s",
+		"diag.implausibleLap":       "невозможная скорость %.3f м/с на круге %d(допустимо %s), вероятно, потеряна отметка",
+		"diag.notStarted":           "так и не стартовал, классифицирован как %s",
+		"diag.notFinished":          "не финишировал(пройдено кругов %d из %d), классифицирован как %s",
+		"diag.unknownAdjustment":    "поправка %s для незарегистрированного участника не учитывается",
+		"diag.ignoredAdjustment":    "поправка %s не учитывается, статус участника %s",
+		"diag.skippedPenaltyLoops":  "пропущено штрафных кругов после огневого рубежа %[2]d: %[1]d(промахов %[3]d, заходов на штрафные круги %[4]d)",
+		"diag.extraPenaltyLoops":    "лишних штрафных кругов после огневого рубежа %[2]d: %[1]d(промахов %[3]d, заходов на штрафные круги %[4]d)",
+		"diag.implausiblePenalty":   "невозможная скорость %.3f м/с на штрафных кругах после огневого рубежа %d(допустимо %s), вероятно, потеряна отметка",
+
+		"err.notRegistered":      "участник(%d) не зарегистрирован",
+		"err.unknownEvent":       "неизвестный id события(%d)",
+		"err.unexpectedPayload":  "неожиданные параметры %T для события %d",
+		"err.targetOutOfRange":   "номер мишени не может быть больше %d(получено %d)",
+		"err.tooManyLaps":        "участник прошёл больше кругов, чем нужно",
+		"err.writeReport":        "не удалось записать отчёт в файл: %v",
+		"err.writeDiagnostics":   "не удалось записать диагностику: %v",
+		"err.raceClosed":         "гонка закрыта в %s, события больше не принимаются",
+		"err.unknownFormat":      "неизвестный формат инпута(%s)",
+		"err.fewParams":          "недостаточно параметров в строке (%s)",
+		"err.eventIdNotInt":      "id события(%s) не число",
+		"err.competitorIdNotInt": "id участника(%s) не число",
+		"err.invalidJSON":        "некорректный json в строке (%s): %v",
+		"err.invalidTime":        "не удалось разобрать время(%s)",
+		"err.invalidDraw":        "некорректное время старта(%s) в событии 2",
+		"err.invalidFiringRange": "некорректный номер огневого рубежа(%s) в событии 5",
+		"err.invalidTarget":      "некорректный номер мишени(%s) в событии 6, ожидается число от 1 до %d",
+		"err.missingReason":      "не указана причина в событии 11",
+		"err.outgoingEvent":      "событие %d генерирует сама система, во входных данных его быть не может",
+		"err.unexpectedParams":   "лишние параметры(%s) у события %d",
+		"err.unknownRule":        "неизвестное правило(%s)",
+		"err.invalidRuleStatus":  "некорректный статус(%s) для правила %s, ожидается DNS, DNF, DSQ, LAP или off",
+		"err.writeSnapshot":      "не удалось записать снимок: %v",
+		"err.readSnapshot":       "не удалось прочитать снимок: %v",
+		"err.snapshotVersion":    "неподдерживаемая версия снимка(%d), ожидается %d",
+		"err.snapshotNoConfig":   "в снимке нет конфига",
+		"err.deltaSign":          "поправка(%s) должна начинаться с + или -",
+		"err.invalidAdjustment":  "некорректное решение жюри в строке %d: %v",
+		"err.adjustmentNoReason": "некорректное решение жюри в строке %d: не указана причина",
+		"err.readAdjustments":    "не удалось прочитать решения жюри: %v",
+		"err.noRaceId":           "не указан id гонки",
+		"err.raceExists":         "гонка(%s) уже зарегистрирована",
+		"err.unknownRace":        "неизвестная гонка(%s)",
+		"err.race":               "гонка(%s): %v",
+		"err.noRaceRoute":        "строка без id гонки(%s)",
+		"err.racesFileNotFound":  "файл гонок не найден в %s",
+		"err.readRacesFile":      "не удалось прочитать файл гонок %s: %v",
+		"err.noRaces":            "в файле гонок %s нет ни одной гонки",
+
+		"report.adjustments": "Решения жюри:",
+		"report.adjustment":  "%d. участник(%d) %s: %s",
+		"report.corrections": "Исправления:",
+		"report.void":        "%d. событие #%d %s аннулировано, автор: %s, затронуты участники: %s",
+		"report.replace":     "%d. событие #%d %s заменено на %s, автор: %s, затронуты участники: %s",
+		"report.insert":      "%d. после события #%[2]d вставлено %[3]s, автор: %[4]s, затронуты участники: %[5]s",
+
+		"validate.malformedLine":     "строка не разобрана: %s",
+		"validate.notChronological":  "события идут не в хронологическом порядке",
+		"validate.unknownCompetitor": "событие %d для неизвестного участника",
+		"validate.illegalSequence":   "недопустимая последовательность: событие %d после события %d: %s",
+		"validate.alreadyFinished":   "участник уже финишировал или сошёл",
+		"validate.drawAfterStart":    "жеребьёвка после старта",
+		"validate.notDrawn":          "время старта не назначено жеребьёвкой",
+		"validate.alreadyStarted":    "участник уже стартовал",
+		"validate.notStarted":        "участник не стартовал",
+		"validate.alreadyOnRange":    "участник уже на огневом рубеже или на штрафных кругах",
+		"validate.notOnRange":        "участник не на огневом рубеже",
+		"validate.penaltyEnter":      "на штрафные круги уходят только после огневого рубежа",
+		"validate.notInPenalty":      "участник не на штрафных кругах",
+		"validate.lapEndOnRange":     "круг не может закончиться на огневом рубеже или на штрафных кругах",
+		"validate.rangeVisits":       "финишировал, побывав на огневых рубежах %d раз из %d",
+		"validate.missingFinish":     "нет финиша: пройдено кругов %d из %d",
+		"validate.file":              "файл",
+		"validate.line":              "строка %d",
+		"validate.problems":          "найдено проблем: %d",
+		"validate.readEvents":        "не удалось прочитать события: %v",
+	},
+}
@@ -0,0 +1,94 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Lang string
+
+const (
+	En Lang = "en"
+	Ru Lang = "ru"
+)
+
+type Localizer struct { //Нулевое значение - английский каталог
+	lang Lang
+}
+
+func New(lang string) (Localizer, error) {
+	switch Lang(lang) {
+	case "", En:
+		return Localizer{lang: En}, nil
+	case Ru:
+		return Localizer{lang: Ru}, nil
+	default:
+		return Localizer{}, fmt.Errorf("unsupported language(%s), expected en or ru", lang)
+	}
+}
+
+func (l Localizer) Lang() Lang {
+	if l.lang == "" {
+		return En
+	}
+	return l.lang
+}
+
+func Register(lang Lang, key, text string) { //Для клубных событий и прочих сообщений вне встроенных каталогов. Вызывать до начала обработки
+	if _, ok := catalogs[lang]; !ok {
+		catalogs[lang] = make(map[string]string)
+	}
+	catalogs[lang][key] = text
+}
+
+func (l Localizer) Lookup(key string) (string, bool) { //Ищем сообщение в каталоге выбранного языка, если там его нет - в английском
+	if text, ok := catalogs[l.Lang()][key]; ok {
+		return text, true
+	}
+	text, ok := catalogs[En][key]
+	return text, ok
+}
+
+func (l Localizer) T(key string, args ...any) string {
+	text, ok := l.Lookup(key)
+	if !ok { //Лучше показать ключ, чем пустую строку
+		text = key
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+func (l Localizer) Errorf(key string, args ...any) error {
+	return errors.New(l.T(key, args...))
+}
+
+type Error struct { //Ошибка, которую переводит тот, кто знает язык(например, ошибки парсера, у которого своего языка нет). Error() - по-английски
+	Key  string
+	Args []any
+}
+
+func Errorf(key string, args ...any) error {
+	return &Error{Key: key, Args: args}
+}
+
+func (e *Error) Error() string {
+	return Localizer{}.T(e.Key, e.Args...)
+}
+
+func (l Localizer) Localize(err error) string { //Текст ошибки на языке локализатора, обёртки(%w) вокруг переводимой ошибки остаются как есть
+	var e *Error
+	if !errors.As(err, &e) {
+		return err.Error()
+	}
+	args := make([]any, len(e.Args))
+	for i, arg := range e.Args {
+		if inner, ok := arg.(error); ok { //Вложенная ошибка, например причина в "invalid adjustment on line %d: %v", тоже переводится
+			arg = l.Localize(inner)
+		}
+		args[i] = arg
+	}
+	return strings.Replace(err.Error(), e.Error(), l.T(e.Key, args...), 1)
+}
@@ -0,0 +1,106 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestLocalizer(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		key  string
+		args []any
+		want string
+	}{
+		{
+			name: "english",
+			lang: "en",
+			key:  "err.notRegistered",
+			args: []any{7},
+			want: "competitor(7) is not registered",
+		},
+		{
+			name: "russian",
+			lang: "ru",
+			key:  "status.DNF",
+			want: "НеФинишировал",
+		},
+		{
+			name: "default language",
+			lang: "",
+			key:  "status.Finished",
+			want: "Finished",
+		},
+		{
+			name: "unknown key",
+			lang: "ru",
+			key:  "no.such.key",
+			want: "no.such.key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := New(tt.lang)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := loc.T(tt.key, tt.args...); got != tt.want {
+				t.Errorf("T() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterFallsBackToEnglish(t *testing.T) {
+	t.Cleanup(func() { //Каталоги глобальные: без этого второй прогон уже найдёт русский перевод
+		delete(catalogs[En], "event.301")
+		delete(catalogs[Ru], "event.301")
+	})
+	Register(En, "event.301", "The competitor({competitor}) changed skis")
+	loc, _ := New("ru")
+	text, ok := loc.Lookup("event.301")
+	if !ok || text != "The competitor({competitor}) changed skis" {
+		t.Errorf("Lookup() = %v, %v", text, ok)
+	}
+
+	Register(Ru, "event.301", "Участник({competitor}) сменил лыжи")
+	if got := loc.T("event.301"); got != "Участник({competitor}) сменил лыжи" {
+		t.Errorf("T() = %v", got)
+	}
+}
+
+func TestNewUnsupportedLanguage(t *testing.T) {
+	if _, err := New("de"); err == nil {
+		t.Error("New() expected error for unsupported language")
+	}
+}
+
+func TestLocalizeKeyedErrors(t *testing.T) {
+	keyed := Errorf("err.notRegistered", 7)
+	ru, _ := New("ru")
+	tests := []struct {
+		name string
+		loc  Localizer
+		err  error
+		want string
+	}{
+		{"english by default", Localizer{}, keyed, "competitor(7) is not registered"},
+		{"russian", ru, keyed, "участник(7) не зарегистрирован"},
+		{"wrapped", ru, fmt.Errorf("line 3: %w", keyed), "line 3: участник(7) не зарегистрирован"},
+		{"plain error stays as is", ru, errors.New("disk is full"), "disk is full"},
+		{"nested", ru, Errorf("err.writeReport", keyed), "не удалось записать отчёт в файл: участник(7) не зарегистрирован"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.loc.Localize(tt.err); got != tt.want {
+				t.Errorf("Localize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if keyed.Error() != "competitor(7) is not registered" {
+		t.Errorf("Error() = %v, want the English text", keyed.Error())
+	}
+}
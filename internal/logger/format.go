@@ -0,0 +1,142 @@
+package loghandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	timeParser "yadro_test/common"
+	"yadro_test/internal/i18n"
+)
+
+type InputFormat interface { //Формат входных строк: каждый из них должен уметь превратить строку в EventInfo
+	Parse(line string) (EventInfo, error)
+}
+
+type TextFormat struct{} //[hh:mm:ss.mmm] eventId competitorId extraParams
+
+type JSONLFormat struct{} //{"time":"10:00:01.744","event":4,"competitor":1,"extra":"..."}
+
+type AutoFormat struct{} //Определяет формат по каждой строке: JSON-объект или текст
+
+type Parser struct { //Только разбирает строки в EventInfo, ничего не пишет, поэтому подходит и для валидаторов, и для других утилит
+	format InputFormat
+}
+
+func NewParser(format InputFormat) *Parser {
+	return &Parser{format: format}
+}
+
+func (p *Parser) ParseLine(line string) (EventInfo, error) {
+	if p.format == nil { //Если формат не задан явно - определяем его по каждой строке
+		return AutoFormat{}.Parse(line)
+	}
+	return p.format.Parse(line)
+}
+
+func FormatByName(name string) (InputFormat, error) {
+	switch name {
+	case "text":
+		return TextFormat{}, nil
+	case "jsonl":
+		return JSONLFormat{}, nil
+	case "auto", "":
+		return AutoFormat{}, nil
+	default:
+		return nil, i18n.Errorf("err.unknownFormat", name)
+	}
+}
+
+func (AutoFormat) Parse(line string) (EventInfo, error) {
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return JSONLFormat{}.Parse(line)
+	}
+	return TextFormat{}.Parse(line)
+}
+
+func (TextFormat) Parse(line string) (EventInfo, error) {
+	line = strings.TrimSpace(line)    //Обрезаем по бокам лишние пробелы на всякий случай
+	parts := strings.Split(line, " ") //Разбиваем на части и обрабатываем случай, если их меньше 3(time eventId compId)
+	if len(parts) < numReqParams {
+		return EventInfo{}, i18n.Errorf("err.fewParams", line)
+	}
+
+	time, eventIdStr, competitorIdStr := parts[0], parts[1], parts[2] //Собираем наши параметры и конвертим их в удобные для работы типы данных
+	eventId, err := strconv.Atoi(eventIdStr)
+	if err != nil {
+		return EventInfo{}, i18n.Errorf("err.eventIdNotInt", eventIdStr)
+	}
+	competitorId, err := strconv.Atoi(competitorIdStr)
+	if err != nil {
+		return EventInfo{}, i18n.Errorf("err.competitorIdNotInt", competitorIdStr)
+	}
+	var extraParams string //Если есть ещё какие-то параметры - забираем их как строчку
+	if len(parts) > numReqParams {
+		extraParams = strings.Join(parts[numReqParams:], " ")
+	}
+
+	eventTime, err := timeParser.ConvertStringToTime(strings.Trim(time, "[]")) //Перевод в удобный тип(time.Time) для работы, у строки по типу [12:00:00.000] обрезаем скобки парсим на время
+	if err != nil {
+		return EventInfo{}, i18n.Errorf("err.invalidTime", strings.Trim(time, "[]"))
+	}
+	payload, err := ParsePayload(eventId, extraParams)
+	if err != nil {
+		return EventInfo{}, err
+	}
+	return EventInfo{ //Полезная структура для работы менеджера в будущем
+		EventId:      eventId,
+		CompetitorId: competitorId,
+		EventTime:    eventTime,
+		ExtraParams:  extraParams,
+		Payload:      payload,
+	}, nil
+}
+
+func (TextFormat) Format(eventInfo EventInfo) string { //Обратное к Parse: событие в виде текстовой строки инпута
+	line := fmt.Sprintf("[%s] %d %d", eventInfo.EventTime.Format("15:04:05.000"), eventInfo.EventId, eventInfo.CompetitorId)
+	if eventInfo.ExtraParams != "" {
+		line += " " + eventInfo.ExtraParams
+	}
+	return line
+}
+
+type jsonEvent struct {
+	Time       *string         `json:"time"`
+	Event      *int            `json:"event"`
+	Competitor *int            `json:"competitor"`
+	Extra      json.RawMessage `json:"extra"` //Может прийти и строкой("10:00:00.000", "Lost in the forest"), и числом(номер мишени)
+}
+
+func (JSONLFormat) Parse(line string) (EventInfo, error) {
+	line = strings.TrimSpace(line)
+	var je jsonEvent
+	if err := json.Unmarshal([]byte(line), &je); err != nil {
+		return EventInfo{}, i18n.Errorf("err.invalidJSON", line, err)
+	}
+	if je.Time == nil || je.Event == nil || je.Competitor == nil { //Все три поля обязательны, как и в текстовом формате
+		return EventInfo{}, i18n.Errorf("err.fewParams", line)
+	}
+
+	var extraParams string
+	if len(je.Extra) > 0 && string(je.Extra) != "null" {
+		if err := json.Unmarshal(je.Extra, &extraParams); err != nil { //Не строка - значит число, берём его как есть
+			extraParams = string(je.Extra)
+		}
+	}
+
+	eventTime, err := timeParser.ConvertStringToTime(strings.Trim(*je.Time, "[]"))
+	if err != nil {
+		return EventInfo{}, i18n.Errorf("err.invalidTime", strings.Trim(*je.Time, "[]"))
+	}
+	payload, err := ParsePayload(*je.Event, extraParams)
+	if err != nil {
+		return EventInfo{}, err
+	}
+	return EventInfo{
+		EventId:      *je.Event,
+		CompetitorId: *je.Competitor,
+		EventTime:    eventTime,
+		ExtraParams:  extraParams,
+		Payload:      payload,
+	}, nil
+}
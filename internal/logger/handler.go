@@ -2,13 +2,14 @@ package loghandler
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log/slog"
-	"os"
 )
 
-type CustomHandler struct {
+type CustomHandler struct { //Человекочитаемый лог: пишет только текст сообщения, структурные атрибуты уходят в JSON-лог
 	slog.Handler
-	logFile *os.File
+	w io.Writer
 }
 
 func (h *CustomHandler) Enabled(context.Context, slog.Level) bool {
@@ -16,7 +17,7 @@ func (h *CustomHandler) Enabled(context.Context, slog.Level) bool {
 }
 
 func (h *CustomHandler) Handle(_ context.Context, r slog.Record) error {
-	_, err := h.logFile.WriteString(r.Message + "\n")
+	_, err := io.WriteString(h.w, r.Message+"\n")
 	return err
 }
 
@@ -27,3 +28,63 @@ func (h *CustomHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 func (h *CustomHandler) WithGroup(name string) slog.Handler {
 	return h
 }
+
+type multiHandler struct { //Раздаёт каждую запись сразу нескольким обработчикам(человекочитаемый лог, JSON и тд)
+	handlers []slog.Handler
+}
+
+func newMultiHandler(handlers ...slog.Handler) slog.Handler {
+	if len(handlers) == 1 {
+		return handlers[0]
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil { //Ошибка одного обработчика не должна мешать остальным
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithAttrs(attrs))
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithGroup(name))
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+func newJSONHandler(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) { //Время записи и уровень не нужны: у события своё время, а пишем мы только Info
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+}
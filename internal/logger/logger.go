@@ -2,92 +2,139 @@ package loghandler
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
-	"os"
-	"strconv"
-	"strings"
 	"time"
+
 	timeParser "yadro_test/common"
+	"yadro_test/internal/i18n"
 )
 
-var mapEvents = map[int]string{ //Для логов хардкодим строчки
-	1:  "The competitor(%d) registered",
-	2:  "The start time for the competitor(%d) was set by a draw to %s",
-	3:  "The competitor(%d) is on the start line",
-	4:  "The competitor(%d) has started",
-	5:  "The competitor(%d) is on the firing range(%s)",
-	6:  "The target(%s) has been hit by competitor(%d)",
-	7:  "The competitor(%d) left the firing range",
-	8:  "The competitor(%d) entered the penalty laps",
-	9:  "The competitor(%d) left the penalty laps",
-	10: "The competitor(%d) ended the main lap",
-	11: "The competitor(%d) can`t continue: %s",
-}
-
 const numReqParams = 3
 
 type EventInfo struct {
 	EventId      int
 	CompetitorId int
-	ExtraParams  string //Здесь будет храниться либо время либо номер стрельбища, цели и тд
+	ExtraParams  string //Здесь будет храниться либо время либо номер стрельбища, цели и тд(в сыром виде, для логов)
 	EventTime    time.Time
+	Payload      Payload          //Те же доп. параметры, но уже разобранные и проверенные(nil, если у события их нет)
+	State        *CompetitorState //Состояние участника после применения события, заполняет менеджер перед передачей наблюдателям
 }
 
-type CustomLogger struct {
-	l *slog.Logger
+type CompetitorState struct { //Производное состояние, чтобы инструментам анализа не нужно было заново проигрывать поток
+	Status      string
+	LapsEnded   int
+	LapTime     time.Duration //Время последнего пройденного круга
+	TotalTime   time.Duration
+	PenaltyTime time.Duration
+	FiringRange int //Сколько огневых рубежей пройдено
+	Hits        int
 }
 
-func NewCustomLogger(logFile *os.File) *CustomLogger {
-	return &CustomLogger{
-		l: slog.New(&CustomHandler{logFile: logFile}),
+func (s CompetitorState) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("status", s.Status),
+		slog.Int("laps_ended", s.LapsEnded),
+		slog.Int("firing_ranges", s.FiringRange),
+		slog.Int("hits", s.Hits),
+	}
+	if s.LapTime != 0 {
+		attrs = append(attrs, slog.String("lap_time", timeParser.ConvertDurationToString(s.LapTime)))
 	}
+	if s.PenaltyTime != 0 {
+		attrs = append(attrs, slog.String("penalty_time", timeParser.ConvertDurationToString(s.PenaltyTime)))
+	}
+	if s.TotalTime != 0 {
+		attrs = append(attrs, slog.String("total_time", timeParser.ConvertDurationToString(s.TotalTime)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+type CustomLogger struct { //Пишет в лог уже принятые менеджером события, сам ничего не разбирает
+	l     *slog.Logger
+	loc   i18n.Localizer
+	sinks []sink
+}
+
+type SinkFormat int
+
+const (
+	SinkText SinkFormat = iota //Человекочитаемые строки, как в output.log
+	SinkJSON                   //JSON-объект на событие со всеми атрибутами
+)
+
+type sink struct {
+	w      io.Writer
+	format SinkFormat
 }
 
-func (cl CustomLogger) ProcessLine(line string) (EventInfo, error) {
-	line = strings.TrimSpace(line)    //Обрезаем по бокам лишние пробелы на всякий случай
-	parts := strings.Split(line, " ") //Разбиваем на части и обрабатываем случай, если их меньше 3(time eventId compId)
-	if len(parts) < numReqParams {
-		return EventInfo{}, fmt.Errorf("insufficient number of parameters in line (%s)", line)
+type Option func(*CustomLogger)
+
+func WithLocalizer(loc i18n.Localizer) Option {
+	return func(cl *CustomLogger) {
+		cl.loc = loc
 	}
+}
 
-	time, eventIdStr, competitorIdStr := parts[0], parts[1], parts[2] //Собираем наши параметры и конвертим их в удобные для работы типы данных
-	eventId, err := strconv.Atoi(eventIdStr)
-	if err != nil {
-		return EventInfo{}, fmt.Errorf("can`t convert eventId(%s) to int", eventIdStr)
+func WithSink(w io.Writer, format SinkFormat) Option { //Дополнительный получатель лога(stdout, сеть, кольцевой буфер) со своим форматом
+	return func(cl *CustomLogger) {
+		cl.sinks = append(cl.sinks, sink{w: w, format: format})
 	}
-	competitorId, err := strconv.Atoi(competitorIdStr)
-	if err != nil {
-		return EventInfo{}, fmt.Errorf("can`t convert competitorId(%s) to int", competitorIdStr)
+}
+
+func WithJSONLog(w io.Writer) Option { //Помимо обычного лога пишем каждое событие JSON-объектом со всеми атрибутами
+	return WithSink(w, SinkJSON)
+}
+
+func NewCustomLogger(w io.Writer, opts ...Option) *CustomLogger {
+	cl := &CustomLogger{sinks: []sink{{w: w, format: SinkText}}}
+	for _, opt := range opts {
+		opt(cl)
 	}
-	var extraParams string //Если есть ещё какие-то параметры - забираем их как строчку
-	if len(parts) > numReqParams {
-		extraParams = strings.Join(parts[numReqParams:], " ")
+
+	handlers := make([]slog.Handler, 0, len(cl.sinks))
+	for _, s := range cl.sinks {
+		if s.format == SinkJSON {
+			handlers = append(handlers, newJSONHandler(s.w))
+		} else {
+			handlers = append(handlers, &CustomHandler{w: s.w})
+		}
 	}
+	cl.l = slog.New(newMultiHandler(handlers...))
+	return cl
+}
 
-	msg := buildLogMessage(time, competitorId, eventId, extraParams) //Построение итоговой строки
-	cl.l.Info(msg)                                                   //Запись в лог-файл
+func (cl CustomLogger) LogEvent(eventInfo EventInfo) {
+	time := fmt.Sprintf("[%s]", eventInfo.EventTime.Format("15:04:05.000"))
+	msg := buildLogMessage(cl.loc, time, eventInfo.CompetitorId, eventInfo.EventId, eventInfo.ExtraParams) //Построение итоговой строки
+	cl.l.Info(msg, eventAttrs(eventInfo)...)                                                               //Запись в лог-файл
+}
 
-	eventTime, err := timeParser.ConvertStringToTime(strings.Trim(time, "[]")) //Перевод в удобный тип(time.Time) для работы, у строки по типу [12:00:00.000] обрезаем скобки парсим на время
-	if err != nil {
-		return EventInfo{}, err
+func eventAttrs(eventInfo EventInfo) []any { //Структурные атрибуты события для JSON-лога
+	attrs := []any{
+		slog.Int("event_id", eventInfo.EventId),
+		slog.Int("competitor_id", eventInfo.CompetitorId),
+		slog.String("event_time", eventInfo.EventTime.Format("15:04:05.000")),
+	}
+	if t, ok := LookupEventType(eventInfo.EventId); ok {
+		attrs = append(attrs, slog.String("event", t.Name))
+	}
+	if eventInfo.ExtraParams != "" {
+		attrs = append(attrs, slog.String("extra", eventInfo.ExtraParams))
+	}
+	if eventInfo.Payload != nil {
+		attrs = append(attrs, slog.Any("payload", eventInfo.Payload))
+	}
+	if eventInfo.State != nil {
+		attrs = append(attrs, slog.Any("state", *eventInfo.State))
 	}
-	return EventInfo{ //Полезная структура для работы менеджера в будущем
-		EventId:      eventId,
-		CompetitorId: competitorId,
-		EventTime:    eventTime,
-		ExtraParams:  extraParams,
-	}, nil
+	return attrs
 }
 
-func buildLogMessage(time string, competitorId, eventId int, extraParams string) string {
-	var eventMsg string
-	switch eventId { //В зависимости от типа события разные параметры передаём(они в разном порядке и количестве идут)
-	case 2, 5, 11:
-		eventMsg = fmt.Sprintf(mapEvents[eventId], competitorId, extraParams)
-	case 6:
-		eventMsg = fmt.Sprintf(mapEvents[eventId], extraParams, competitorId)
-	default:
-		eventMsg = fmt.Sprintf(mapEvents[eventId], competitorId)
+func buildLogMessage(loc i18n.Localizer, time string, competitorId, eventId int, extraParams string) string {
+	t, ok := LookupEventType(eventId) //Шаблон и порядок параметров берём из реестра типов событий
+	if !ok {
+		return fmt.Sprintf("%s %s", time, loc.T("event.unknown", eventId, competitorId))
 	}
-	return fmt.Sprintf("%s %s", time, eventMsg)
+	return fmt.Sprintf("%s %s", time, t.Message(loc, competitorId, extraParams))
 }
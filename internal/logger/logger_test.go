@@ -2,27 +2,26 @@ package loghandler
 
 import (
 	"bytes"
-	"log/slog"
+	"encoding/json"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"yadro_test/internal/i18n"
 )
 
 func TestNewCustomLogger(t *testing.T) {
-	tmpfile, err := os.CreateTemp("", "testlog")
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-	defer os.Remove(tmpfile.Name())
-	defer tmpfile.Close()
-
-	logger := NewCustomLogger(tmpfile)
+	logger := NewCustomLogger(new(bytes.Buffer))
 	if logger == nil {
 		t.Error("NewCustomLogger() returned nil")
 	}
 }
 
-func TestProcessLineValidInput(t *testing.T) {
+func TestParseLineValidInput(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
@@ -48,6 +47,7 @@ func TestProcessLineValidInput(t *testing.T) {
 				CompetitorId: 100,
 				EventTime:    time.Date(0, 1, 1, 12, 34, 56, 789000000, time.UTC),
 				ExtraParams:  "13:00:00.000",
+				Payload:      DrawEvent{StartAt: time.Date(0, 1, 1, 13, 0, 0, 0, time.UTC)},
 			},
 			wantErr: false,
 		},
@@ -59,40 +59,41 @@ func TestProcessLineValidInput(t *testing.T) {
 				CompetitorId: 100,
 				EventTime:    time.Date(0, 1, 1, 12, 34, 56, 789000000, time.UTC),
 				ExtraParams:  "1",
+				Payload:      HitEvent{Target: 1},
 			},
 			wantErr: false,
 		},
 	}
 
-	buf := new(bytes.Buffer)
-	logger := &CustomLogger{
-		l: slog.New(slog.NewTextHandler(buf, nil)),
-	}
+	parser := NewParser(TextFormat{})
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := logger.ProcessLine(tt.input)
+			got, err := parser.ParseLine(tt.input)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("ProcessLine() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseLine() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if got.EventId != tt.expected.EventId {
-				t.Errorf("ProcessLine() EventId = %v, want %v", got.EventId, tt.expected.EventId)
+				t.Errorf("ParseLine() EventId = %v, want %v", got.EventId, tt.expected.EventId)
 			}
 			if got.CompetitorId != tt.expected.CompetitorId {
-				t.Errorf("ProcessLine() CompetitorId = %v, want %v", got.CompetitorId, tt.expected.CompetitorId)
+				t.Errorf("ParseLine() CompetitorId = %v, want %v", got.CompetitorId, tt.expected.CompetitorId)
 			}
 			if !got.EventTime.Equal(tt.expected.EventTime) {
-				t.Errorf("ProcessLine() EventTime = %v, want %v", got.EventTime, tt.expected.EventTime)
+				t.Errorf("ParseLine() EventTime = %v, want %v", got.EventTime, tt.expected.EventTime)
 			}
 			if got.ExtraParams != tt.expected.ExtraParams {
-				t.Errorf("ProcessLine() ExtraParams = %v, want %v", got.ExtraParams, tt.expected.ExtraParams)
+				t.Errorf("ParseLine() ExtraParams = %v, want %v", got.ExtraParams, tt.expected.ExtraParams)
+			}
+			if got.Payload != tt.expected.Payload {
+				t.Errorf("ParseLine() Payload = %v, want %v", got.Payload, tt.expected.Payload)
 			}
 		})
 	}
 }
 
-func TestProcessLineInvalidInput(t *testing.T) {
+func TestParseLineInvalidInput(t *testing.T) {
 	tests := []struct {
 		name    string
 		input   string
@@ -128,21 +129,39 @@ func TestProcessLineInvalidInput(t *testing.T) {
 			input:   "[12:34:56] 1 100",
 			wantErr: "unable to parse time.Time(12:34:56)",
 		},
+		{
+			name:    "invalid draw time",
+			input:   "[12:34:56.789] 2 100 25:00",
+			wantErr: "invalid draw time(25:00) for event 2",
+		},
+		{
+			name:    "target out of range",
+			input:   "[12:34:56.789] 6 100 6",
+			wantErr: "invalid target(6) for event 6, expected number from 1 to 5",
+		},
+		{
+			name:    "missing reason",
+			input:   "[12:34:56.789] 11 100",
+			wantErr: "missing reason for event 11",
+		},
+		{
+			name:    "unexpected extra params",
+			input:   "[12:34:56.789] 4 100 now",
+			wantErr: "unexpected extra params(now) for event 4",
+		},
 	}
 
-	logger := &CustomLogger{
-		l: slog.New(slog.NewTextHandler(os.Stderr, nil)),
-	}
+	parser := NewParser(TextFormat{})
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := logger.ProcessLine(tt.input)
+			_, err := parser.ParseLine(tt.input)
 			if err == nil {
-				t.Errorf("ProcessLine() expected error, got nil")
+				t.Errorf("ParseLine() expected error, got nil")
 				return
 			}
 			if err.Error() != tt.wantErr {
-				t.Errorf("ProcessLine() error = %v, wantErr %v", err.Error(), tt.wantErr)
+				t.Errorf("ParseLine() error = %v, wantErr %v", err.Error(), tt.wantErr)
 			}
 		})
 	}
@@ -185,10 +204,354 @@ func TestBuildLogMessage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := buildLogMessage(tt.time, tt.competitorId, tt.eventId, tt.extraParams)
+			got := buildLogMessage(i18n.Localizer{}, tt.time, tt.competitorId, tt.eventId, tt.extraParams)
 			if got != tt.expectedMsg {
 				t.Errorf("buildLogMessage() = %v, want %v", got, tt.expectedMsg)
 			}
 		})
 	}
 }
+
+func TestParseLineJSONL(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected EventInfo
+		wantErr  string
+	}{
+		{
+			name:  "event without extra",
+			input: `{"time":"10:00:01.744","event":4,"competitor":1}`,
+			expected: EventInfo{
+				EventId:      4,
+				CompetitorId: 1,
+				EventTime:    time.Date(0, 1, 1, 10, 0, 1, 744000000, time.UTC),
+			},
+		},
+		{
+			name:  "string extra",
+			input: `{"time":"09:55:00.000","event":2,"competitor":1,"extra":"10:00:00.000"}`,
+			expected: EventInfo{
+				EventId:      2,
+				CompetitorId: 1,
+				EventTime:    time.Date(0, 1, 1, 9, 55, 0, 0, time.UTC),
+				ExtraParams:  "10:00:00.000",
+				Payload:      DrawEvent{StartAt: time.Date(0, 1, 1, 10, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:  "numeric extra",
+			input: `{"time":"10:08:50.884","event":6,"competitor":1,"extra":3}`,
+			expected: EventInfo{
+				EventId:      6,
+				CompetitorId: 1,
+				EventTime:    time.Date(0, 1, 1, 10, 8, 50, 884000000, time.UTC),
+				ExtraParams:  "3",
+				Payload:      HitEvent{Target: 3},
+			},
+		},
+		{
+			name:    "missing competitor",
+			input:   `{"time":"10:00:01.744","event":4}`,
+			wantErr: `insufficient number of parameters in line ({"time":"10:00:01.744","event":4})`,
+		},
+		{
+			name:    "invalid time",
+			input:   `{"time":"10:00","event":4,"competitor":1}`,
+			wantErr: "unable to parse time.Time(10:00)",
+		},
+	}
+
+	parser := NewParser(JSONLFormat{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseLine(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ParseLine() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLine() unexpected error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseLine() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAutoFormat(t *testing.T) {
+	text, err := AutoFormat{}.Parse("[10:00:01.744] 4 1")
+	if err != nil {
+		t.Fatalf("AutoFormat.Parse() text error = %v", err)
+	}
+	jsonl, err := AutoFormat{}.Parse(`{"time":"10:00:01.744","event":4,"competitor":1}`)
+	if err != nil {
+		t.Fatalf("AutoFormat.Parse() jsonl error = %v", err)
+	}
+	if text != jsonl {
+		t.Errorf("AutoFormat.Parse() text = %+v, jsonl = %+v", text, jsonl)
+	}
+
+	if _, err := FormatByName("xml"); err == nil {
+		t.Error("FormatByName() expected error for unknown format")
+	}
+}
+
+func TestRegisterEventType(t *testing.T) {
+	t.Cleanup(func() { UnregisterEventType(101) })
+	err := RegisterEventType(EventType{
+		Id:       101,
+		Name:     "ski change",
+		Template: "The competitor({competitor}) changed skis: {extra}",
+		Payload: func(extraParams string) (Payload, error) {
+			return NotFinishedEvent{Reason: extraParams}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterEventType() error = %v", err)
+	}
+	if err := RegisterEventType(EventType{Id: 101, Template: "again"}); err == nil {
+		t.Error("RegisterEventType() expected error for duplicate id")
+	}
+	if err := RegisterEventType(EventType{Id: 102}); err == nil {
+		t.Error("RegisterEventType() expected error for empty template")
+	}
+
+	got, err := NewParser(nil).ParseLine("[10:00:00.000] 101 1 broken binding")
+	if err != nil {
+		t.Fatalf("ParseLine() error = %v", err)
+	}
+	if got.ExtraParams != "broken binding" {
+		t.Errorf("ParseLine() ExtraParams = %v, want broken binding", got.ExtraParams)
+	}
+
+	msg := buildLogMessage(i18n.Localizer{}, "[10:00:00.000]", 1, 101, "broken binding")
+	if msg != "[10:00:00.000] The competitor(1) changed skis: broken binding" {
+		t.Errorf("buildLogMessage() = %v", msg)
+	}
+}
+
+func TestParseLineUnknownOrOutgoingEvent(t *testing.T) {
+	parser := NewParser(nil)
+	_, err := parser.ParseLine("[10:00:00.000] 42 1")
+	if err == nil || err.Error() != "unknown event id(42)" {
+		t.Errorf("ParseLine() error = %v, want unknown event id(42)", err)
+	}
+	_, err = parser.ParseLine("[10:00:00.000] 33 1")
+	if err == nil || err.Error() != "event id(33) is generated by the system and can`t be in the input" {
+		t.Errorf("ParseLine() error = %v, want outgoing event error", err)
+	}
+}
+
+func TestBuildLogMessageRussian(t *testing.T) {
+	loc, err := i18n.New("ru")
+	if err != nil {
+		t.Fatalf("i18n.New() error = %v", err)
+	}
+	got := buildLogMessage(loc, "[12:34:56.789]", 100, 6, "3")
+	if got != "[12:34:56.789] Мишень(3) поражена участником(100)" {
+		t.Errorf("buildLogMessage() = %v", got)
+	}
+}
+
+func TestJSONLog(t *testing.T) {
+	textBuf := new(bytes.Buffer)
+	jsonBuf := new(bytes.Buffer)
+	logger := NewCustomLogger(textBuf, WithJSONLog(jsonBuf))
+	eventInfo, err := NewParser(nil).ParseLine("[10:08:50.884] 6 1 3")
+	if err != nil {
+		t.Fatalf("ParseLine() error = %v", err)
+	}
+	logger.LogEvent(eventInfo)
+
+	var got map[string]any
+	if err := json.Unmarshal(jsonBuf.Bytes(), &got); err != nil {
+		t.Fatalf("JSON log is not valid json(%s): %v", jsonBuf.String(), err)
+	}
+	want := map[string]any{
+		"msg":           "[10:08:50.884] The target(3) has been hit by competitor(1)",
+		"event_id":      float64(6),
+		"competitor_id": float64(1),
+		"event_time":    "10:08:50.884",
+		"event":         "target hit",
+		"extra":         "3",
+		"payload":       map[string]any{"target": float64(3)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSON log = %v, want %v", got, want)
+	}
+
+	if textBuf.String() != "[10:08:50.884] The target(3) has been hit by competitor(1)\n" {
+		t.Errorf("Text log = %q", textBuf.String())
+	}
+}
+
+func TestMultipleSinks(t *testing.T) {
+	registrationTimes := map[int]time.Time{
+		3: time.Date(0, 1, 1, 9, 31, 49, 285000000, time.UTC),
+		2: time.Date(0, 1, 1, 9, 32, 17, 531000000, time.UTC),
+		5: time.Date(0, 1, 1, 9, 37, 47, 892000000, time.UTC),
+	}
+	file := new(bytes.Buffer)
+	stdout := new(bytes.Buffer)
+	ring := NewRingBuffer(2)
+	logger := NewCustomLogger(file, WithSink(stdout, SinkText), WithSink(ring, SinkText))
+
+	for _, competitorId := range []int{3, 2, 5} {
+		logger.LogEvent(EventInfo{EventId: 1, CompetitorId: competitorId, EventTime: registrationTimes[competitorId]})
+	}
+
+	want := "[09:31:49.285] The competitor(3) registered\n" +
+		"[09:32:17.531] The competitor(2) registered\n" +
+		"[09:37:47.892] The competitor(5) registered\n"
+	if file.String() != want || stdout.String() != want {
+		t.Errorf("Sinks got file = %q, stdout = %q, want %q", file.String(), stdout.String(), want)
+	}
+	wantRing := []string{"[09:32:17.531] The competitor(2) registered", "[09:37:47.892] The competitor(5) registered"}
+	if !reflect.DeepEqual(ring.Lines(), wantRing) {
+		t.Errorf("RingBuffer.Lines() = %v, want %v", ring.Lines(), wantRing)
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	rb := NewRingBuffer(3)
+	if len(rb.Lines()) != 0 {
+		t.Errorf("Expected empty ring buffer, got %v", rb.Lines())
+	}
+	rb.Write([]byte("a\nb\n"))
+	if !reflect.DeepEqual(rb.Lines(), []string{"a", "b"}) {
+		t.Errorf("RingBuffer.Lines() = %v", rb.Lines())
+	}
+	rb.Write([]byte("c\nd\n"))
+	if !reflect.DeepEqual(rb.Lines(), []string{"b", "c", "d"}) {
+		t.Errorf("RingBuffer.Lines() = %v", rb.Lines())
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := RaceLogPath(dir, "sprint", time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC))
+	if filepath.Base(path) != "sprint_2026-01-15.log" {
+		t.Fatalf("RaceLogPath() = %v", path)
+	}
+
+	rf, err := OpenRotatingFile(path, 40, "=== header ===")
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	for _, line := range []string{"first line of the log\n", "second line of the log\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	archived, err := os.ReadFile(filepath.Join(dir, "sprint_2026-01-15.1.log"))
+	if err != nil {
+		t.Fatalf("Archived log not found: %v", err)
+	}
+	if string(archived) != "=== header ===\nfirst line of the log\n" {
+		t.Errorf("Archived log = %q", archived)
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Current log not found: %v", err)
+	}
+	if string(current) != "=== header ===\nsecond line of the log\n" {
+		t.Errorf("Current log = %q", current)
+	}
+}
+
+func TestRaceHeader(t *testing.T) {
+	header, err := RaceHeader("sprint", time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC), map[string]int{"laps": 2})
+	if err != nil {
+		t.Fatalf("RaceHeader() error = %v", err)
+	}
+	want := `=== race sprint started at 2026-01-15T09:00:00Z, config: {"laps":2} ===`
+	if header != want {
+		t.Errorf("RaceHeader() = %v, want %v", header, want)
+	}
+}
+
+func TestParseErrorsAreLocalized(t *testing.T) {
+	ru, _ := i18n.New("ru")
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"[10:00:00.000] 6 1 9", "некорректный номер мишени(9) в событии 6, ожидается число от 1 до 5"},
+		{"[10:00:00.000] 42 1", "неизвестный id события(42)"},
+		{"[10:00:00.000] x 1", "id события(x) не число"},
+		{`{"time":"10:00:00.000","event":11,"competitor":1}`, "не указана причина в событии 11"},
+	}
+	for _, tt := range tests {
+		_, err := NewParser(nil).ParseLine(tt.line)
+		if err == nil {
+			t.Fatalf("ParseLine(%q) expected error", tt.line)
+		}
+		if got := ru.Localize(err); got != tt.want {
+			t.Errorf("Localize(ParseLine(%q)) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestJSONLogDerivedState(t *testing.T) {
+	jsonBuf := new(bytes.Buffer)
+	logger := NewCustomLogger(io.Discard, WithJSONLog(jsonBuf))
+	eventInfo, err := NewParser(nil).ParseLine("[10:25:18.356] 10 2")
+	if err != nil {
+		t.Fatalf("ParseLine() error = %v", err)
+	}
+	eventInfo.State = &CompetitorState{Status: "Finished", LapsEnded: 2, LapTime: 12*time.Minute + 38610*time.Millisecond, TotalTime: 25*time.Minute + 18356*time.Millisecond, FiringRange: 2, Hits: 8}
+	logger.LogEvent(eventInfo)
+
+	var got struct {
+		State map[string]any `json:"state"`
+	}
+	if err := json.Unmarshal(jsonBuf.Bytes(), &got); err != nil {
+		t.Fatalf("JSON log is not valid json(%s): %v", jsonBuf.String(), err)
+	}
+	want := map[string]any{
+		"status":        "Finished",
+		"laps_ended":    float64(2),
+		"firing_ranges": float64(2),
+		"hits":          float64(8),
+		"lap_time":      "00:12:38.610",
+		"total_time":    "00:25:18.356",
+	}
+	if !reflect.DeepEqual(got.State, want) {
+		t.Errorf("JSON log state = %v, want %v", got.State, want)
+	}
+}
+
+func TestRotatingFileArchiveError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, strings.Repeat("a", 251)+".log") //Имя архива(.1.log) длиннее 255 символов - Stat вернёт ошибку, но не "файла нет"
+	rf, err := OpenRotatingFile(path, 10, "")
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	if _, err := rf.Write([]byte("first line\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := rf.Write([]byte("second line\n"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Write() expected error when the archive name can`t be checked")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write() hangs when the archive name can`t be checked")
+	}
+}
@@ -0,0 +1,83 @@
+package loghandler
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+	timeParser "yadro_test/common"
+	"yadro_test/internal/i18n"
+)
+
+const TargetsPerFiringLine = 5
+
+type Payload interface { //Типизированные доп. параметры события, String() возвращает их в том виде, в каком они пишутся во входной строке
+	String() string
+}
+
+type DrawEvent struct { //Событие 2: время старта по жеребьёвке
+	StartAt time.Time
+}
+
+type FiringRangeEvent struct { //Событие 5: номер огневого рубежа
+	Range int
+}
+
+type HitEvent struct { //Событие 6: номер поражённой мишени
+	Target int
+}
+
+type NotFinishedEvent struct { //Событие 11: почему участник не может продолжить
+	Reason string
+}
+
+func (p DrawEvent) String() string        { return p.StartAt.Format("15:04:05.000") }
+func (p FiringRangeEvent) String() string { return strconv.Itoa(p.Range) }
+func (p HitEvent) String() string         { return strconv.Itoa(p.Target) }
+func (p NotFinishedEvent) String() string { return p.Reason }
+
+func (p DrawEvent) LogValue() slog.Value { //Разобранные параметры попадают в JSON-лог отдельными полями
+	return slog.GroupValue(slog.String("start_at", p.String()))
+}
+
+func (p FiringRangeEvent) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("range", p.Range))
+}
+
+func (p HitEvent) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("target", p.Target))
+}
+
+func (p NotFinishedEvent) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("reason", p.Reason))
+}
+
+func parseDraw(extraParams string) (Payload, error) {
+	startAt, err := timeParser.ConvertStringToTime(extraParams)
+	if err != nil {
+		return nil, i18n.Errorf("err.invalidDraw", extraParams)
+	}
+	return DrawEvent{StartAt: startAt}, nil
+}
+
+func parseFiringRange(extraParams string) (Payload, error) {
+	rangeNum, err := strconv.Atoi(extraParams)
+	if err != nil || rangeNum < 1 {
+		return nil, i18n.Errorf("err.invalidFiringRange", extraParams)
+	}
+	return FiringRangeEvent{Range: rangeNum}, nil
+}
+
+func parseHit(extraParams string) (Payload, error) {
+	target, err := strconv.Atoi(extraParams)
+	if err != nil || target < 1 || target > TargetsPerFiringLine {
+		return nil, i18n.Errorf("err.invalidTarget", extraParams, TargetsPerFiringLine)
+	}
+	return HitEvent{Target: target}, nil
+}
+
+func parseNotFinished(extraParams string) (Payload, error) {
+	if extraParams == "" {
+		return nil, i18n.Errorf("err.missingReason")
+	}
+	return NotFinishedEvent{Reason: extraParams}, nil
+}
@@ -0,0 +1,90 @@
+package loghandler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yadro_test/internal/i18n"
+)
+
+type EventType struct {
+	Id       int
+	Name     string
+	Template string                                    //Шаблон строки лога, вместо {competitor} и {extra} подставляются id участника и доп. параметры. Перевод ищется в каталоге по ключу event.<id>
+	Payload  func(extraParams string) (Payload, error) //Разбор и проверка доп. параметров, nil - у события их быть не должно
+	Outgoing bool                                      //Событие генерирует сам менеджер, во входных данных его быть не может
+	Key      string                                    //Ключ перевода, если он отличается от event.<id>(например, у события закрытия гонки с id из конфига)
+}
+
+var eventTypes = map[int]EventType{ //Встроенные события из тз, их шаблоны лежат в каталогах i18n. Регистрировать новые типы нужно до начала обработки событий
+	1:  {Id: 1, Name: "registered"},
+	2:  {Id: 2, Name: "draw", Payload: parseDraw},
+	3:  {Id: 3, Name: "on start line"},
+	4:  {Id: 4, Name: "started"},
+	5:  {Id: 5, Name: "on firing range", Payload: parseFiringRange},
+	6:  {Id: 6, Name: "target hit", Payload: parseHit},
+	7:  {Id: 7, Name: "left firing range"},
+	8:  {Id: 8, Name: "entered penalty laps"},
+	9:  {Id: 9, Name: "left penalty laps"},
+	10: {Id: 10, Name: "lap ended"},
+	11: {Id: 11, Name: "can`t continue", Payload: parseNotFinished},
+	12: {Id: 12, Name: "race closed"}, //Id события закрытия можно поменять в конфиге(closeEventId), тогда оно регистрируется дополнительно
+	32: {Id: 32, Name: "disqualified", Outgoing: true},
+	33: {Id: 33, Name: "finished", Outgoing: true},
+}
+
+func RegisterEventType(t EventType) error {
+	if t.Id <= 0 {
+		return fmt.Errorf("event id must be positive(got %d)", t.Id)
+	}
+	if _, ok := eventTypes[t.Id]; ok {
+		return fmt.Errorf("event id(%d) is already registered", t.Id)
+	}
+	if t.Template == "" {
+		return fmt.Errorf("event id(%d) has no log message template", t.Id)
+	}
+	eventTypes[t.Id] = t
+	return nil
+}
+
+func UnregisterEventType(id int) { //Только для тестов: реестр глобальный, и зарегистрированный тестом тип не должен пережить тест
+	delete(eventTypes, id)
+}
+
+func LookupEventType(id int) (EventType, bool) {
+	t, ok := eventTypes[id]
+	return t, ok
+}
+
+func ParsePayload(eventId int, extraParams string) (Payload, error) { //Проверяем доп. параметры сразу при разборе, чтобы кривые данные не доходили до менеджера
+	t, ok := LookupEventType(eventId)
+	if !ok {
+		return nil, i18n.Errorf("err.unknownEvent", eventId)
+	}
+	if t.Outgoing {
+		return nil, i18n.Errorf("err.outgoingEvent", eventId)
+	}
+	if t.Payload == nil {
+		if extraParams != "" {
+			return nil, i18n.Errorf("err.unexpectedParams", extraParams, eventId)
+		}
+		return nil, nil
+	}
+	return t.Payload(extraParams)
+}
+
+func (t EventType) Message(loc i18n.Localizer, competitorId int, extraParams string) string {
+	key := t.Key
+	if key == "" {
+		key = fmt.Sprintf("event.%d", t.Id)
+	}
+	template, ok := loc.Lookup(key)
+	if !ok {
+		template = t.Template
+	}
+	return strings.NewReplacer(
+		"{competitor}", strconv.Itoa(competitorId),
+		"{extra}", extraParams,
+	).Replace(template)
+}
@@ -0,0 +1,44 @@
+package loghandler
+
+import (
+	"strings"
+	"sync"
+)
+
+type RingBuffer struct { //Хранит последние size строк лога в памяти, например чтобы отдавать их через API
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func NewRingBuffer(size int) *RingBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &RingBuffer{lines: make([]string, size)}
+}
+
+func (rb *RingBuffer) Write(p []byte) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") { //Обработчики пишут целыми строками, но на всякий случай режем по \n
+		rb.lines[rb.next] = line
+		rb.next = (rb.next + 1) % len(rb.lines)
+		if rb.next == 0 {
+			rb.full = true
+		}
+	}
+	return len(p), nil
+}
+
+func (rb *RingBuffer) Lines() []string { //Строки от самой старой к самой новой
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if !rb.full {
+		return append([]string(nil), rb.lines[:rb.next]...)
+	}
+	return append(append([]string(nil), rb.lines[rb.next:]...), rb.lines[:rb.next]...)
+}
@@ -0,0 +1,105 @@
+package loghandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RotatingFile struct { //Лог-файл гонки, который при превышении maxSize уезжает в <имя>.N.log, а запись продолжается в новый файл
+	mu      sync.Mutex
+	path    string
+	maxSize int64 //0 - без ротации
+	header  string
+	file    *os.File
+	size    int64
+}
+
+func RaceLogPath(dir, raceId string, date time.Time) string { //Для каждой гонки свой файл, чтобы логи за сезон не смешивались
+	return filepath.Join(dir, fmt.Sprintf("%s_%s.log", raceId, date.Format("2006-01-02")))
+}
+
+func RaceHeader(raceId string, startedAt time.Time, config any) (string, error) { //Первая строка лога гонки с эффективным конфигом
+	cfgJSON, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal config for log header: %v", err)
+	}
+	return fmt.Sprintf("=== race %s started at %s, config: %s ===", raceId, startedAt.Format(time.RFC3339), cfgJSON), nil
+}
+
+func OpenRotatingFile(path string, maxSize int64, header string) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	rf := &RotatingFile{path: path, maxSize: maxSize, header: header}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.file.Close()
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file, rf.size = file, info.Size()
+
+	if rf.header != "" { //Заголовок пишем при каждом открытии: так в логе видны и начало гонки, и перезапуски
+		n, err := rf.file.WriteString(rf.header + "\n")
+		rf.size += int64(n)
+		return err
+	}
+	return nil
+}
+
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	base := strings.TrimSuffix(rf.path, filepath.Ext(rf.path))
+	for i := 1; ; i += 1 { //Ищем первый свободный номер архива
+		archived := fmt.Sprintf("%s.%d%s", base, i, filepath.Ext(rf.path))
+		_, err := os.Stat(archived)
+		if err == nil { //Номер занят - пробуем следующий
+			continue
+		}
+		if !os.IsNotExist(err) { //Нет прав на папку и тд: дальше перебирать бессмысленно, иначе запись в лог зависнет навсегда
+			return err
+		}
+		if err := os.Rename(rf.path, archived); err != nil {
+			return err
+		}
+		break
+	}
+	return rf.open()
+}
@@ -0,0 +1,176 @@
+package merger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	timeParser "yadro_test/common"
+)
+
+type Source struct {
+	Name   string //Имя источника(обычно имя файла), нужно для понятных ошибок
+	Reader io.Reader
+}
+
+type Options struct {
+	Offsets     map[string]time.Duration //Поправка часов по имени источника, прибавляется ко времени каждого события
+	SyncEventId int                      //Если не 0 - поправки оцениваются автоматически по первому такому событию в каждом источнике
+}
+
+type Result struct {
+	Lines      []string                 //Итоговый поток событий, отсортированный по времени
+	Duplicates int                      //Сколько точных дубликатов было выкинуто
+	Offsets    map[string]time.Duration //Какие поправки в итоге были применены к источникам
+}
+
+type line struct {
+	raw     string
+	time    time.Time
+	eventId int
+}
+
+func ParseOffsets(offsets map[string]string) (map[string]time.Duration, error) { //Поправки в конфиге задаются строками вида "-300ms", "1.5s"
+	parsed := make(map[string]time.Duration, len(offsets))
+	for name, offset := range offsets {
+		dur, err := time.ParseDuration(offset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clock offset for source %s(%s)", name, offset)
+		}
+		parsed[name] = dur
+	}
+	return parsed, nil
+}
+
+func MergeFiles(paths []string, opts Options) (Result, error) {
+	sources := make([]Source, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return Result{}, err
+		}
+		defer f.Close()
+		sources = append(sources, Source{Name: filepath.Base(path), Reader: f}) //В конфиге поправок источники указываются по имени файла
+	}
+	return Merge(sources, opts)
+}
+
+func Merge(sources []Source, opts Options) (Result, error) {
+	srcLines := make([][]line, len(sources))
+	for i, src := range sources { //Сначала вычитываем все источники целиком
+		lines, err := readSource(src)
+		if err != nil {
+			return Result{}, err
+		}
+		srcLines[i] = lines
+	}
+
+	offsets := estimateOffsets(sources, srcLines, opts.SyncEventId)
+	for name, offset := range opts.Offsets { //Явно заданные поправки важнее оценённых автоматически
+		offsets[name] = offset
+	}
+
+	var lines []line
+	for i, src := range sources {
+		offset := offsets[src.Name]
+		for _, l := range srcLines[i] {
+			if opts.SyncEventId != 0 && l.eventId == opts.SyncEventId { //Событие синхронизации нужно только для оценки поправок, дальше его не передаём
+				continue
+			}
+			if offset != 0 {
+				l = l.shifted(offset)
+			}
+			lines = append(lines, l)
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { //Стабильная сортировка: при равном времени сохраняется порядок источников и строк в них
+		return lines[i].time.Before(lines[j].time)
+	})
+
+	res := Result{Lines: make([]string, 0, len(lines)), Offsets: offsets}
+	seen := make(map[string]struct{}, len(lines))
+	for _, l := range lines { //Точные дубликаты(одно и то же событие с разных устройств) оставляем в единственном экземпляре
+		if _, ok := seen[l.raw]; ok {
+			res.Duplicates += 1
+			continue
+		}
+		seen[l.raw] = struct{}{}
+		res.Lines = append(res.Lines, l.raw)
+	}
+	return res, nil
+}
+
+func readSource(src Source) ([]line, error) {
+	var lines []line
+	scanner := bufio.NewScanner(src.Reader)
+	for lineNum := 1; scanner.Scan(); lineNum += 1 {
+		raw := strings.Join(strings.Fields(scanner.Text()), " ") //Нормализуем пробелы, чтобы одинаковые события с разным форматированием считались дубликатами
+		if raw == "" {
+			continue
+		}
+		t, err := lineTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", src.Name, lineNum, err)
+		}
+		lines = append(lines, line{raw: raw, time: t, eventId: lineEventId(raw)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", src.Name, err)
+	}
+	return lines, nil
+}
+
+func lineTime(raw string) (time.Time, error) {
+	timeStr, _, _ := strings.Cut(raw, " ")
+	if !strings.HasPrefix(timeStr, "[") || !strings.HasSuffix(timeStr, "]") {
+		return time.Time{}, fmt.Errorf("time must be in square brackets(%s)", timeStr)
+	}
+	return timeParser.ConvertStringToTime(strings.Trim(timeStr, "[]"))
+}
+
+func lineEventId(raw string) int { //Id события нужен только для поиска события синхронизации, поэтому кривой id просто считаем нулевым
+	parts := strings.Fields(raw)
+	if len(parts) < 2 {
+		return 0
+	}
+	eventId, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+	return eventId
+}
+
+func (l line) shifted(offset time.Duration) line { //Сдвигаем время события и переписываем его в самой строке, чтобы менеджер получил уже исправленное время
+	l.time = l.time.Add(offset)
+	_, rest, _ := strings.Cut(l.raw, " ")
+	l.raw = fmt.Sprintf("[%s] %s", l.time.Format("15:04:05.000"), rest)
+	return l
+}
+
+func estimateOffsets(sources []Source, srcLines [][]line, syncEventId int) map[string]time.Duration {
+	offsets := make(map[string]time.Duration, len(sources))
+	if syncEventId == 0 {
+		return offsets
+	}
+	var reference time.Time //Опорными считаем часы первого источника, в котором есть событие синхронизации
+	for i, src := range sources {
+		for _, l := range srcLines[i] {
+			if l.eventId != syncEventId {
+				continue
+			}
+			if reference.IsZero() {
+				reference = l.time
+			}
+			offsets[src.Name] = reference.Sub(l.time)
+			break
+		}
+	}
+	return offsets
+}
@@ -0,0 +1,121 @@
+package merger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	start := Source{Name: "start", Reader: strings.NewReader(
+		"[09:55:00.000] 2 1 10:00:00.000\n" +
+			"[10:00:01.744] 4 1\n",
+	)}
+	firing := Source{Name: "firing", Reader: strings.NewReader(
+		"[10:08:49.289] 5 1 1\n" +
+			"\n" +
+			"[10:00:01.744]  4 1\n" + //Тот же старт, продублированный другим устройством
+			"[09:59:45.000] 3 1\n",
+	)}
+
+	res, err := Merge([]Source{start, firing}, Options{})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+
+	want := []string{
+		"[09:55:00.000] 2 1 10:00:00.000",
+		"[09:59:45.000] 3 1",
+		"[10:00:01.744] 4 1",
+		"[10:08:49.289] 5 1 1",
+	}
+	if !reflect.DeepEqual(res.Lines, want) {
+		t.Errorf("Merge() lines = %v, want %v", res.Lines, want)
+	}
+	if res.Duplicates != 1 {
+		t.Errorf("Merge() duplicates = %d, want 1", res.Duplicates)
+	}
+}
+
+func TestMergeKeepsSourceOrderForEqualTimes(t *testing.T) {
+	a := Source{Name: "a", Reader: strings.NewReader("[10:00:00.000] 6 1 1\n")}
+	b := Source{Name: "b", Reader: strings.NewReader("[10:00:00.000] 6 1 2\n")}
+
+	res, err := Merge([]Source{a, b}, Options{})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	want := []string{"[10:00:00.000] 6 1 1", "[10:00:00.000] 6 1 2"}
+	if !reflect.DeepEqual(res.Lines, want) {
+		t.Errorf("Merge() lines = %v, want %v", res.Lines, want)
+	}
+}
+
+func TestMergeInvalidLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "time without brackets",
+			input:   "10:00:00.000 1 1",
+			wantErr: "finish:1: time must be in square brackets(10:00:00.000)",
+		},
+		{
+			name:    "invalid time",
+			input:   "[10:00:00.000] 1 1\n[10:00] 1 2",
+			wantErr: "finish:2: unable to parse time.Time(10:00)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Merge([]Source{{Name: "finish", Reader: strings.NewReader(tt.input)}}, Options{})
+			if err == nil {
+				t.Fatal("Merge() expected error, got nil")
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("Merge() error = %v, wantErr %v", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeWithClockOffsets(t *testing.T) {
+	finish := Source{Name: "finish", Reader: strings.NewReader("[10:12:35.380] 10 1\n")}
+	firing := Source{Name: "range", Reader: strings.NewReader("[10:12:35.600] 7 1\n")} //Часы рубежа спешат на 300мс
+
+	res, err := Merge([]Source{finish, firing}, Options{Offsets: map[string]time.Duration{"range": -300 * time.Millisecond}})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	want := []string{"[10:12:35.300] 7 1", "[10:12:35.380] 10 1"}
+	if !reflect.DeepEqual(res.Lines, want) {
+		t.Errorf("Merge() lines = %v, want %v", res.Lines, want)
+	}
+}
+
+func TestMergeEstimatesOffsetsFromSyncEvent(t *testing.T) {
+	finish := Source{Name: "finish", Reader: strings.NewReader(
+		"[09:00:00.000] 99 0\n" +
+			"[10:12:35.380] 10 1\n",
+	)}
+	firing := Source{Name: "range", Reader: strings.NewReader(
+		"[09:00:00.300] 99 0\n" +
+			"[10:12:35.600] 7 1\n",
+	)}
+
+	res, err := Merge([]Source{finish, firing}, Options{SyncEventId: 99})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	want := []string{"[10:12:35.300] 7 1", "[10:12:35.380] 10 1"}
+	if !reflect.DeepEqual(res.Lines, want) {
+		t.Errorf("Merge() lines = %v, want %v", res.Lines, want)
+	}
+	if res.Offsets["range"] != -300*time.Millisecond {
+		t.Errorf("Merge() range offset = %v, want -300ms", res.Offsets["range"])
+	}
+}
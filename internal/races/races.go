@@ -0,0 +1,128 @@
+package races
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"yadro_test/internal/cfg"
+	cmptmgr "yadro_test/internal/competitionMgr"
+	"yadro_test/internal/i18n"
+	lh "yadro_test/internal/logger"
+)
+
+type Race struct { //Одна гонка фестиваля: свой конфиг, свой менеджер в своей горутине, свой лог и отчёт
+	Id     string
+	Config *cfg.Config
+	Loop   *cmptmgr.EventLoop //Менеджер гонки доступен только через него, так что события разных источников не гоняются за его состоянием
+}
+
+type Registry struct { //Все гонки, которые идут в одном процессе, по id гонки. Методы можно звать из разных горутин
+	mu    sync.RWMutex
+	races map[string]*Race
+}
+
+func NewRegistry() *Registry {
+	return &Registry{races: make(map[string]*Race)}
+}
+
+func (r *Registry) Add(config *cfg.Config, manager *cmptmgr.CompetitionManager) (*Race, error) { //Запускает менеджер в своей горутине, дальше напрямую его не трогаем
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if config.RaceId == "" {
+		return nil, i18n.Errorf("err.noRaceId")
+	}
+	if _, ok := r.races[config.RaceId]; ok {
+		return nil, i18n.Errorf("err.raceExists", config.RaceId)
+	}
+	race := &Race{Id: config.RaceId, Config: config, Loop: manager.Run()}
+	r.races[race.Id] = race
+	return race, nil
+}
+
+func (r *Registry) Race(id string) (*Race, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	race, ok := r.races[id]
+	return race, ok
+}
+
+func (r *Registry) Races() []*Race { //По возрастанию id, чтобы отчёты и ошибки шли в одном порядке
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	races := make([]*Race, 0, len(r.races))
+	for _, race := range r.races {
+		races = append(races, race)
+	}
+	sort.Slice(races, func(i, j int) bool {
+		return races[i].Id < races[j].Id
+	})
+	return races
+}
+
+func (r *Registry) HandleEvent(raceId string, eventInfo lh.EventInfo) error { //Событие уходит в горутину менеджера своей гонки
+	race, ok := r.Race(raceId)
+	if !ok {
+		return i18n.Errorf("err.unknownRace", raceId)
+	}
+	return race.Loop.HandleEvent(eventInfo)
+}
+
+func (r *Registry) Close() error { //Закрываем все ещё идущие гонки, ошибка одной гонки не мешает закрыть остальные
+	var errs []error
+	for _, race := range r.Races() {
+		if err := race.Loop.Close(); err != nil {
+			errs = append(errs, i18n.Errorf("err.race", race.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) Stop() { //Останавливает горутины всех менеджеров, после этого вызовы возвращают ErrLoopStopped
+	for _, race := range r.Races() {
+		race.Loop.Stop()
+	}
+}
+
+func SplitRoute(line string) (string, string, error) { //Строка общего потока: id гонки, затем обычное событие, например "sprint [10:00:01.744] 4 1"
+	raceId, event, ok := strings.Cut(strings.TrimSpace(line), " ")
+	if !ok || raceId == "" || strings.HasPrefix(raceId, "[") || strings.HasPrefix(raceId, "{") {
+		return "", "", i18n.Errorf("err.noRaceRoute", line)
+	}
+	return raceId, strings.TrimSpace(event), nil
+}
+
+func LoadConfigs(path string) ([]*cfg.Config, error) { //Файл фестиваля: {"sprint-men": "sprint.json", "mass-women": "mass.json"}, пути - относительно него
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, i18n.Errorf("err.racesFileNotFound", path)
+	}
+	var paths map[string]string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return nil, i18n.Errorf("err.readRacesFile", path, err)
+	}
+	if len(paths) == 0 {
+		return nil, i18n.Errorf("err.noRaces", path)
+	}
+
+	configs := make([]*cfg.Config, 0, len(paths))
+	for raceId, cfgPath := range paths {
+		if !filepath.IsAbs(cfgPath) {
+			cfgPath = filepath.Join(filepath.Dir(path), cfgPath)
+		}
+		config, err := cfg.Load(cfgPath)
+		if err != nil {
+			return nil, i18n.Errorf("err.race", raceId, err)
+		}
+		config.RaceId = raceId //Id из файла фестиваля главнее: по нему маршрутизируются события, из него же имя лога
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].RaceId < configs[j].RaceId
+	})
+	return configs, nil
+}
@@ -0,0 +1,186 @@
+package races
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"yadro_test/internal/cfg"
+	cmptmgr "yadro_test/internal/competitionMgr"
+	"yadro_test/internal/i18n"
+	lh "yadro_test/internal/logger"
+)
+
+func addRace(t *testing.T, r *Registry, raceId string, laps int) *bytes.Buffer {
+	t.Helper()
+	out := new(bytes.Buffer)
+	config := &cfg.Config{RaceId: raceId, Laps: laps, LapLen: 1000, StartDelta: "00:01:30", CloseEventId: cmptmgr.EventRaceClosed}
+	if _, err := r.Add(config, cmptmgr.NewCompetitionManager(out, config)); err != nil {
+		t.Fatalf("Add(%s) failed: %v", raceId, err)
+	}
+	return out
+}
+
+func TestRegistryRoutesByRaceId(t *testing.T) {
+	r := NewRegistry()
+	defer r.Stop()
+	sprintOut := addRace(t, r, "sprint", 1)
+	massOut := addRace(t, r, "mass", 2)
+
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []struct {
+		raceId string
+		event  lh.EventInfo
+	}{
+		{"sprint", lh.EventInfo{EventId: 1, CompetitorId: 1, EventTime: startTime}},
+		{"mass", lh.EventInfo{EventId: 1, CompetitorId: 1, EventTime: startTime}}, //Тот же номер участника в другой гонке - это другой участник
+		{"sprint", lh.EventInfo{EventId: 2, CompetitorId: 1, ExtraParams: "12:00:00.000", EventTime: startTime}},
+		{"sprint", lh.EventInfo{EventId: 4, CompetitorId: 1, EventTime: startTime.Add(time.Second)}},
+		{"sprint", lh.EventInfo{EventId: 10, CompetitorId: 1, EventTime: startTime.Add(5 * time.Minute)}},
+		{"sprint", lh.EventInfo{EventId: cmptmgr.EventRaceClosed, EventTime: startTime.Add(6 * time.Minute)}},
+	}
+	for _, e := range events {
+		if err := r.HandleEvent(e.raceId, e.event); err != nil {
+			t.Fatalf("HandleEvent(%s) failed: %v", e.raceId, err)
+		}
+	}
+
+	if err := r.HandleEvent("sprint", lh.EventInfo{EventId: 1, CompetitorId: 2, EventTime: startTime.Add(7 * time.Minute)}); !errors.Is(err, cmptmgr.ErrRaceClosed) {
+		t.Errorf("HandleEvent() on a closed race error = %v, want ErrRaceClosed", err)
+	}
+	if err := r.HandleEvent("mass", lh.EventInfo{EventId: 1, CompetitorId: 2, EventTime: startTime.Add(7 * time.Minute)}); err != nil {
+		t.Errorf("Closing one race must not close the others, got %v", err)
+	}
+	if err := r.HandleEvent("relay", lh.EventInfo{EventId: 1, CompetitorId: 1, EventTime: startTime}); err == nil || !strings.Contains(err.Error(), "unknown race(relay)") {
+		t.Errorf("HandleEvent() for an unknown race error = %v", err)
+	}
+	ru, _ := i18n.New("ru")
+	if got := ru.Localize(r.HandleEvent("relay", lh.EventInfo{EventId: 1, CompetitorId: 1, EventTime: startTime})); got != "неизвестная гонка(relay)" {
+		t.Errorf("Localize(HandleEvent()) for an unknown race = %q", got)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if got, want := sprintOut.String(), "[00:05:00.000] 1 [{00:05:00.000, 3.333}] {,} 0/0\n"; got != want {
+		t.Errorf("sprint report = %q, want %q", got, want)
+	}
+	if got, want := massOut.String(), "[NotStarted] 1 [{,}, {,}] {,} 0/0 noStart\n[NotStarted] 2 [{,}, {,}] {,} 0/0 noStart\n"; got != want {
+		t.Errorf("mass report = %q, want %q", got, want)
+	}
+
+	var ids []string
+	for _, race := range r.Races() {
+		ids = append(ids, race.Id)
+	}
+	if strings.Join(ids, ",") != "mass,sprint" {
+		t.Errorf("Races() = %v, want [mass sprint]", ids)
+	}
+}
+
+func TestRegistryAddErrors(t *testing.T) {
+	r := NewRegistry()
+	defer r.Stop()
+	addRace(t, r, "sprint", 1)
+	config := &cfg.Config{RaceId: "sprint"}
+	if _, err := r.Add(config, cmptmgr.NewCompetitionManager(new(bytes.Buffer), config)); err == nil {
+		t.Error("Add() with a duplicate race id must fail")
+	}
+	config = &cfg.Config{}
+	if _, err := r.Add(config, cmptmgr.NewCompetitionManager(new(bytes.Buffer), config)); err == nil {
+		t.Error("Add() without a race id must fail")
+	}
+}
+
+func TestRegistryConcurrentEvents(t *testing.T) {
+	r := NewRegistry()
+	addRace(t, r, "sprint", 1)
+	addRace(t, r, "mass", 1)
+	startTime := time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	const perSource = 50
+	var wg sync.WaitGroup
+	for source := 0; source < 4; source++ { //По два источника событий на каждую гонку
+		raceId := []string{"sprint", "mass"}[source%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= perSource; i++ {
+				e := lh.EventInfo{EventId: 1, CompetitorId: source*perSource + i, EventTime: startTime.Add(time.Duration(i) * time.Second)}
+				if err := r.HandleEvent(raceId, e); err != nil {
+					t.Errorf("HandleEvent(%s) failed: %v", raceId, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, race := range r.Races() {
+		standings, err := race.Loop.Standings()
+		if err != nil {
+			t.Fatalf("Standings(%s) failed: %v", race.Id, err)
+		}
+		if len(standings) != 2*perSource {
+			t.Errorf("race %s has %d competitors, want %d", race.Id, len(standings), 2*perSource)
+		}
+	}
+
+	r.Stop()
+	if err := r.HandleEvent("sprint", lh.EventInfo{EventId: 1, CompetitorId: 1000, EventTime: startTime}); !errors.Is(err, cmptmgr.ErrLoopStopped) {
+		t.Errorf("HandleEvent() after Stop error = %v, want ErrLoopStopped", err)
+	}
+}
+
+func TestSplitRoute(t *testing.T) {
+	tests := []struct {
+		line       string
+		wantRace   string
+		wantEvent  string
+		wantErrNil bool
+	}{
+		{"sprint [10:00:01.744] 4 1", "sprint", "[10:00:01.744] 4 1", true},
+		{`mass {"time":"10:00:01.744","event":4,"competitor":1}`, "mass", `{"time":"10:00:01.744","event":4,"competitor":1}`, true},
+		{"[10:00:01.744] 4 1", "", "", false},
+		{"sprint", "", "", false},
+	}
+	for _, tt := range tests {
+		raceId, event, err := SplitRoute(tt.line)
+		if (err == nil) != tt.wantErrNil || raceId != tt.wantRace || event != tt.wantEvent {
+			t.Errorf("SplitRoute(%q) = %q, %q, %v", tt.line, raceId, event, err)
+		}
+	}
+}
+
+func TestLoadConfigs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sprint.json"), []byte(`{"raceId": "ignored", "laps": 3}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	racesPath := filepath.Join(dir, "races.json")
+	if err := os.WriteFile(racesPath, []byte(`{"sprint-men": "sprint.json", "sprint-women": "sprint.json"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configs, err := LoadConfigs(racesPath)
+	if err != nil {
+		t.Fatalf("LoadConfigs() failed: %v", err)
+	}
+	if len(configs) != 2 || configs[0].RaceId != "sprint-men" || configs[1].RaceId != "sprint-women" || configs[0].Laps != 3 {
+		t.Errorf("LoadConfigs() = %+v, %+v", configs[0], configs[1])
+	}
+	if configs[0] == configs[1] {
+		t.Error("Races must not share one config")
+	}
+
+	if err := os.WriteFile(racesPath, []byte(`{"relay": "missing.json"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigs(racesPath); err == nil || !strings.Contains(err.Error(), "race(relay)") {
+		t.Errorf("LoadConfigs() with a missing config error = %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package validator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"yadro_test/internal/cfg"
+	cmptmgr "yadro_test/internal/competitionMgr"
+	"yadro_test/internal/i18n"
+	lh "yadro_test/internal/logger"
+)
+
+const ( //Код возврата для пайплайна загрузки
+	ExitOK       = 0
+	ExitWarnings = 1
+	ExitErrors   = 2
+)
+
+type Issue struct {
+	Line         int //Номер строки в файле, на которой нашлась проблема, 0 - найдена уже после разбора всего файла
+	CompetitorId int
+	Severity     cmptmgr.Severity
+	Message      string
+}
+
+type Report struct {
+	Issues []Issue
+	loc    i18n.Localizer //Язык config.lang, нулевое значение - английский
+}
+
+type competitorState struct { //Где сейчас находится участник - по этому проверяем допустимость следующего события
+	lastEvent   int
+	drawn       bool
+	started     bool
+	onRange     bool
+	inPenalty   bool
+	finished    bool
+	rangeVisits int
+	lapsEnded   int
+}
+
+type validation struct {
+	config      *cfg.Config
+	loc         i18n.Localizer
+	report      Report
+	competitors map[int]*competitorState
+	lastTime    time.Time
+}
+
+func Validate(input io.Reader, config *cfg.Config, format lh.InputFormat) (Report, error) {
+	loc, err := i18n.New(config.Lang)
+	if err != nil {
+		return Report{}, err
+	}
+	if err := cmptmgr.RegisterCloseEvent(config.CloseEventId); err != nil {
+		return Report{}, err
+	}
+	v := &validation{config: config, loc: loc, report: Report{loc: loc}, competitors: make(map[int]*competitorState)}
+	parser := lh.NewParser(format)
+	cm := cmptmgr.NewCompetitionManager(io.Discard, config) //Результаты не нужны, менеджер прогоняем ради его собственных проверок
+
+	seen := 0 //Сколько диагностик менеджера уже перенесено в отчёт
+	scanner := bufio.NewScanner(input)
+	for lineNum := 1; scanner.Scan(); lineNum += 1 {
+		eventInfo, err := parser.ParseLine(scanner.Text())
+		if err != nil { //Битая строка или неизвестный id события
+			v.add(lineNum, 0, cmptmgr.Error, "validate.malformedLine", loc.Localize(err))
+			continue
+		}
+		if !v.checkSequence(lineNum, eventInfo) {
+			continue
+		}
+		err = cm.HandleEvent(eventInfo)
+		if errors.Is(err, cmptmgr.ErrRaceClosed) { //Основной запуск такие события пропускает, это не ошибка файла
+			v.addMessage(lineNum, eventInfo.CompetitorId, cmptmgr.Warning, err.Error())
+		} else if err != nil {
+			v.addMessage(lineNum, eventInfo.CompetitorId, cmptmgr.Error, err.Error())
+		}
+		seen = v.addDiagnostics(lineNum, cm.Diagnostics(), seen)
+	}
+	if err := scanner.Err(); err != nil {
+		return Report{}, loc.Errorf("validate.readEvents", err)
+	}
+
+	v.addDiagnostics(0, cm.Diagnostics(), seen)
+	v.checkResults(cm.Competitors(), config)
+	return v.report, nil
+}
+
+func (v *validation) add(line, competitorId int, severity cmptmgr.Severity, key string, args ...any) {
+	v.addMessage(line, competitorId, severity, v.loc.T(key, args...))
+}
+
+func (v *validation) addMessage(line, competitorId int, severity cmptmgr.Severity, message string) { //Для уже переведённых сообщений менеджера
+	v.report.Issues = append(v.report.Issues, Issue{
+		Line:         line,
+		CompetitorId: competitorId,
+		Severity:     severity,
+		Message:      message,
+	})
+}
+
+func (v *validation) addDiagnostics(line int, diags []cmptmgr.Diagnostic, seen int) int { //Дубликаты, конфликты и неправдоподобные скорости, найденные менеджером, - с номером строки, на которой их нашли
+	for _, d := range diags[seen:] {
+		v.addMessage(line, d.CompetitorId, d.Severity, d.Message)
+	}
+	return len(diags)
+}
+
+func (v *validation) checkSequence(line int, e lh.EventInfo) bool { //Возвращает false, если событие не стоит отдавать менеджеру
+	if !v.lastTime.IsZero() && e.EventTime.Before(v.lastTime) {
+		v.add(line, e.CompetitorId, cmptmgr.Warning, "validate.notChronological")
+	}
+	v.lastTime = e.EventTime
+
+	if v.config.CloseEventId != 0 && e.EventId == v.config.CloseEventId { //Закрытие гонки - событие оператора, участника у него нет
+		return true
+	}
+	state, ok := v.competitors[e.CompetitorId]
+	if e.EventId == 1 {
+		if !ok {
+			v.competitors[e.CompetitorId] = &competitorState{lastEvent: 1}
+		}
+		return true //Повторную регистрацию разберёт менеджер
+	}
+	if !ok {
+		v.add(line, e.CompetitorId, cmptmgr.Error, "validate.unknownCompetitor", e.EventId)
+		return false
+	}
+
+	if illegal := state.illegalReason(e.EventId); illegal != "" {
+		v.add(line, e.CompetitorId, cmptmgr.Error, "validate.illegalSequence", e.EventId, state.lastEvent, v.loc.T(illegal))
+	}
+	state.apply(e.EventId)
+	if state.lapsEnded >= v.config.Laps { //Прошёл все круги - дальше от него событий быть не должно
+		state.finished = true
+	}
+	return true
+}
+
+func (s *competitorState) illegalReason(eventId int) string { //Ключ каталога с причиной, пусто - событие допустимо
+	if s.finished {
+		return "validate.alreadyFinished"
+	}
+	switch eventId {
+	case 2:
+		if s.started {
+			return "validate.drawAfterStart"
+		}
+	case 3, 4:
+		if !s.drawn {
+			return "validate.notDrawn"
+		}
+		if eventId == 4 && s.started {
+			return "validate.alreadyStarted"
+		}
+	case 5:
+		if !s.started {
+			return "validate.notStarted"
+		}
+		if s.onRange || s.inPenalty {
+			return "validate.alreadyOnRange"
+		}
+	case 6, 7:
+		if !s.onRange {
+			return "validate.notOnRange"
+		}
+	case 8:
+		if !s.started || s.onRange || s.inPenalty {
+			return "validate.penaltyEnter"
+		}
+	case 9:
+		if !s.inPenalty {
+			return "validate.notInPenalty"
+		}
+	case 10:
+		if !s.started {
+			return "validate.notStarted"
+		}
+		if s.onRange || s.inPenalty {
+			return "validate.lapEndOnRange"
+		}
+	}
+	return ""
+}
+
+func (s *competitorState) apply(eventId int) {
+	switch eventId {
+	case 2:
+		s.drawn = true
+	case 4:
+		s.started = true
+	case 5:
+		s.onRange = true
+	case 7:
+		s.onRange = false
+		s.rangeVisits += 1
+	case 8:
+		s.inPenalty = true
+	case 9:
+		s.inPenalty = false
+	case 10:
+		s.lapsEnded += 1
+	case 11:
+		s.finished = true
+	}
+	s.lastEvent = eventId
+}
+
+func (v *validation) checkResults(competitors []*cmptmgr.Competitor, config *cfg.Config) {
+	for _, c := range competitors {
+		state := v.competitors[c.CompetitorId]
+		switch c.Status {
+		case cmptmgr.StatusFinished:
+			if state.rangeVisits < config.FiringLines {
+				v.add(0, c.CompetitorId, cmptmgr.Error, "validate.rangeVisits", state.rangeVisits, config.FiringLines)
+			}
+		default:
+			if !c.Status.Terminal() && state.started {
+				v.add(0, c.CompetitorId, cmptmgr.Warning, "validate.missingFinish", c.LapsEnded, config.Laps)
+			}
+		}
+	}
+}
+
+func (r Report) ExitCode() int {
+	code := ExitOK
+	for _, issue := range r.Issues {
+		if issue.Severity == cmptmgr.Error {
+			return ExitErrors
+		}
+		code = ExitWarnings
+	}
+	return code
+}
+
+func (r Report) Write(w io.Writer) error {
+	for _, issue := range r.Issues {
+		where := r.loc.T("validate.file")
+		if issue.Line > 0 {
+			where = r.loc.T("validate.line", issue.Line)
+		}
+		if issue.CompetitorId != 0 {
+			where += " " + r.loc.T("diagnostics.competitor", issue.CompetitorId)
+		}
+		severity := r.loc.T("diagnostics.warning")
+		if issue.Severity == cmptmgr.Error {
+			severity = r.loc.T("diagnostics.error")
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s: %s\n", where, severity, issue.Message); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, r.loc.T("validate.problems", len(r.Issues)))
+	return err
+}
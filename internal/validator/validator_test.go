@@ -0,0 +1,189 @@
+package validator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"yadro_test/internal/cfg"
+	cmptmgr "yadro_test/internal/competitionMgr"
+)
+
+func testConfig() *cfg.Config {
+	return &cfg.Config{Laps: 1, LapLen: 3500, PenaltyLen: 150, FiringLines: 1, StartDelta: "00:01:30", MaxLapSpeed: 12}
+}
+
+func TestValidateCleanFile(t *testing.T) {
+	events := `[09:00:00.000] 1 1
+[09:00:01.000] 2 1 10:00:00.000
+[10:00:00.500] 4 1
+[10:05:00.000] 5 1 1
+[10:05:01.000] 6 1 1
+[10:05:02.000] 6 1 2
+[10:05:03.000] 6 1 3
+[10:05:04.000] 6 1 4
+[10:05:05.000] 6 1 5
+[10:05:06.000] 7 1
+[10:12:00.000] 10 1
+`
+	report, err := Validate(strings.NewReader(events), testConfig(), nil)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(report.Issues) != 0 {
+		report.Write(os.Stderr)
+		t.Fatalf("Expected no issues, got %d", len(report.Issues))
+	}
+	if report.ExitCode() != ExitOK {
+		t.Errorf("ExitCode() = %d, want %d", report.ExitCode(), ExitOK)
+	}
+}
+
+func TestValidateProblems(t *testing.T) {
+	events := `[09:00:00.000] 1 1
+[09:00:01.000] 2 1 10:00:00.000
+garbage
+[09:00:02.000] 42 1
+[09:00:03.000] 4 7
+[10:00:00.500] 4 1
+[10:00:10.000] 6 1 1
+[10:01:00.000] 10 1
+[09:30:00.000] 1 2
+[09:30:01.000] 2 2 10:01:00.000
+[10:01:00.000] 4 2
+`
+	report, err := Validate(strings.NewReader(events), testConfig(), nil)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	want := []struct {
+		line     int
+		severity cmptmgr.Severity
+		contains string
+	}{
+		{3, cmptmgr.Error, "malformed line"},
+		{4, cmptmgr.Error, "unknown event id(42)"},
+		{5, cmptmgr.Error, "unknown competitor"},
+		{7, cmptmgr.Error, "competitor is not on the firing range"},
+		{8, cmptmgr.Error, "impossible speed"}, //Диагностика менеджера - с номером строки, на которой он её нашёл
+		{9, cmptmgr.Warning, "not in chronological order"},
+		{0, cmptmgr.Error, "firing range visits"},
+		{0, cmptmgr.Warning, "missing finish event"},
+	}
+	if len(report.Issues) != len(want) {
+		report.Write(os.Stderr)
+		t.Fatalf("Expected %d issues, got %d", len(want), len(report.Issues))
+	}
+	for i, w := range want {
+		got := report.Issues[i]
+		if got.Line != w.line || got.Severity != w.severity || !strings.Contains(got.Message, w.contains) {
+			t.Errorf("Issue %d = %+v, want line %d %v containing %q", i, got, w.line, w.severity, w.contains)
+		}
+	}
+	if report.ExitCode() != ExitErrors {
+		t.Errorf("ExitCode() = %d, want %d", report.ExitCode(), ExitErrors)
+	}
+}
+
+func TestExitCodeWarningsOnly(t *testing.T) {
+	report := Report{Issues: []Issue{{Severity: cmptmgr.Warning}}}
+	if report.ExitCode() != ExitWarnings {
+		t.Errorf("ExitCode() = %d, want %d", report.ExitCode(), ExitWarnings)
+	}
+}
+
+func TestValidateCloseEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		closeEventId int
+	}{
+		{"built-in close event", 12},
+		{"close event id from config", 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := fmt.Sprintf(`[09:00:00.000] 1 1
+[09:00:01.000] 2 1 10:00:00.000
+[10:00:00.500] 4 1
+[10:05:00.000] 5 1 1
+[10:05:01.000] 6 1 1
+[10:05:02.000] 6 1 2
+[10:05:03.000] 6 1 3
+[10:05:04.000] 6 1 4
+[10:05:05.000] 6 1 5
+[10:05:06.000] 7 1
+[10:12:00.000] 10 1
+[10:30:00.000] %d 0
+`, tt.closeEventId)
+			config := testConfig()
+			config.CloseEventId = tt.closeEventId
+			report, err := Validate(strings.NewReader(events), config, nil)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if len(report.Issues) != 0 {
+				report.Write(os.Stderr)
+				t.Fatalf("Expected no issues, got %d", len(report.Issues))
+			}
+			if report.ExitCode() != ExitOK {
+				t.Errorf("ExitCode() = %d, want %d", report.ExitCode(), ExitOK)
+			}
+		})
+	}
+}
+
+func TestValidateEventsAfterClose(t *testing.T) {
+	events := `[09:00:00.000] 1 1
+[09:30:00.000] 12 0
+[09:31:00.000] 1 2
+`
+	config := testConfig()
+	config.CloseEventId = 12
+	report, err := Validate(strings.NewReader(events), config, nil)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if last := report.Issues[len(report.Issues)-1]; last.Line != 3 || last.Severity != cmptmgr.Warning || !strings.Contains(last.Message, "race was closed") {
+		report.Write(os.Stderr)
+		t.Fatalf("Expected a warning for the event after the close, got %+v", report.Issues)
+	}
+	if first := report.Issues[0]; first.Line != 2 || !strings.Contains(first.Message, "never started") { //Финализация при закрытии - на строке события закрытия
+		t.Errorf("Expected the finalization warning on the close line, got %+v", first)
+	}
+	if report.ExitCode() != ExitWarnings {
+		t.Errorf("ExitCode() = %d, want %d", report.ExitCode(), ExitWarnings)
+	}
+}
+
+func TestValidateRussian(t *testing.T) {
+	events := `[09:00:00.000] 1 1
+garbage
+[09:00:02.000] 42 1
+[09:00:03.000] 4 7
+`
+	config := testConfig()
+	config.Lang = "ru"
+	report, err := Validate(strings.NewReader(events), config, nil)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	out := new(strings.Builder)
+	if err := report.Write(out); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := `строка 2: ОШИБКА: строка не разобрана: недостаточно параметров в строке (garbage)
+строка 3: ОШИБКА: строка не разобрана: неизвестный id события(42)
+строка 4 участник(7): ОШИБКА: событие 4 для неизвестного участника
+найдено проблем: 3
+`
+	if out.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	config.Lang = "de"
+	if _, err := Validate(strings.NewReader(events), config, nil); err == nil {
+		t.Error("Validate() expected error for an unsupported language")
+	}
+}